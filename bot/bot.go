@@ -0,0 +1,198 @@
+// Package bot steers snakes without a human behind them
+// The simulator uses it to balance configs, one Strategy instance drives one snake
+package bot
+
+import (
+	"fmt"
+	"github.com/moneygames-io/gameserver/engine"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+type Strategy interface {
+	// Decides the input for the player's snake this tick, only called while it's alive
+	Next(g *engine.Game, id engine.PlayerID) engine.Input
+}
+
+// Builds a named strategy, see Names for what's available
+func New(name string, r *rand.Rand) (Strategy, error) {
+	switch {
+	case name == "straight":
+		return &Straight{}, nil
+	case name == "random":
+		return &Random{Rand: r, TurnChance: 0.2, SprintChance: 0.05}, nil
+	case name == "greedy":
+		return &Greedy{Rand: r, Radius: 10}, nil
+	case strings.HasPrefix(name, "script:"):
+		return ParseScript(strings.TrimPrefix(name, "script:"))
+	default:
+		return nil, fmt.Errorf("unknown bot %q", name)
+	}
+}
+
+// Strategy names New accepts, script takes a comma separated list of directions after the colon
+func Names() []string {
+	return []string{"straight", "random", "greedy", "script:<directions>"}
+}
+
+// Difficulty levels for server controlled snakes, easiest first
+var Difficulties = []string{"easy", "medium", "hard"}
+
+// Builds the strategy the server uses for a difficulty level
+func ForDifficulty(level string, r *rand.Rand) (Strategy, error) {
+	switch level {
+	case "easy":
+		return &Random{Rand: r, TurnChance: 0.3}, nil
+	case "medium":
+		return &Greedy{Rand: r, Radius: 5}, nil
+	case "hard":
+		return &Greedy{Rand: r, Radius: 20}, nil
+	default:
+		return nil, fmt.Errorf("unknown difficulty %q", level)
+	}
+}
+
+// Keeps going the way the snake spawned facing
+type Straight struct {
+	Direction int
+}
+
+func (b *Straight) Next(g *engine.Game, id engine.PlayerID) engine.Input {
+	return engine.Input{Turns: []int{b.Direction}}
+}
+
+// Plays back a fixed list of directions one per tick, starting over when it runs out
+type Script struct {
+	Directions []int
+	next       int
+}
+
+func ParseScript(script string) (*Script, error) {
+	b := &Script{}
+	for _, field := range strings.Split(script, ",") {
+		direction, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || direction < engine.Up || direction > engine.Left {
+			return nil, fmt.Errorf("bad direction %q in script", field)
+		}
+		b.Directions = append(b.Directions, direction)
+	}
+	return b, nil
+}
+
+func (b *Script) Next(g *engine.Game, id engine.PlayerID) engine.Input {
+	direction := b.Directions[b.next%len(b.Directions)]
+	b.next++
+	return engine.Input{Turns: []int{direction}}
+}
+
+// Wanders, turning and sprinting at random but never straight into something it can see
+type Random struct {
+	Rand         *rand.Rand
+	TurnChance   float64
+	SprintChance float64
+	direction    int
+}
+
+func (b *Random) Next(g *engine.Game, id engine.PlayerID) engine.Input {
+	head := g.ActivePlayers[id]
+
+	if b.Rand.Float64() < b.TurnChance || !Safe(g, head, b.direction) {
+		b.direction = pick(b.Rand, SafeDirections(g, head), b.direction)
+	}
+
+	return engine.Input{
+		Turns:     []int{b.direction},
+		Sprinting: b.Rand.Float64() < b.SprintChance,
+	}
+}
+
+// Heads for the closest food within Radius, avoiding anything it would die on
+type Greedy struct {
+	Rand      *rand.Rand
+	Radius    int
+	direction int
+}
+
+func (b *Greedy) Next(g *engine.Game, id engine.PlayerID) engine.Input {
+	head := g.ActivePlayers[id]
+	safe := SafeDirections(g, head)
+
+	food, found := NearestFood(g, head, b.Radius)
+	if found {
+		best := -1
+		for _, direction := range safe {
+			dRow, dCol := engine.DirectionToRowCol(direction)
+			distance := abs(food.Row-head.Row-dRow) + abs(food.Col-head.Col-dCol)
+			if best == -1 || distance < best {
+				best = distance
+				b.direction = direction
+			}
+		}
+		return engine.Input{Turns: []int{b.direction}}
+	}
+
+	if !Safe(g, head, b.direction) {
+		b.direction = pick(b.Rand, safe, b.direction)
+	}
+	return engine.Input{Turns: []int{b.direction}}
+}
+
+// Whether moving the head one step in direction lands on an empty tile or food
+func Safe(g *engine.Game, head *engine.SnakeNode, direction int) bool {
+	dRow, dCol := engine.DirectionToRowCol(direction)
+
+	switch g.Get(&engine.Coordinate{Row: head.Row + dRow, Col: head.Col + dCol}).(type) {
+	case nil, *engine.FoodNode:
+		return true
+	default:
+		return false
+	}
+}
+
+func SafeDirections(g *engine.Game, head *engine.SnakeNode) []int {
+	var directions []int
+	for direction := engine.Up; direction <= engine.Left; direction++ {
+		if Safe(g, head, direction) {
+			directions = append(directions, direction)
+		}
+	}
+	return directions
+}
+
+// Closest food by manhattan distance in the square of the given radius around head
+func NearestFood(g *engine.Game, head *engine.SnakeNode, radius int) (engine.Coordinate, bool) {
+	var nearest engine.Coordinate
+	best := -1
+
+	for row := head.Row - radius; row <= head.Row+radius; row++ {
+		for col := head.Col - radius; col <= head.Col+radius; col++ {
+			if _, food := g.Get(&engine.Coordinate{Row: row, Col: col}).(*engine.FoodNode); !food {
+				continue
+			}
+
+			distance := abs(row-head.Row) + abs(col-head.Col)
+			if best == -1 || distance < best {
+				best = distance
+				nearest = engine.Coordinate{Row: row, Col: col}
+			}
+		}
+	}
+
+	return nearest, best != -1
+}
+
+// Random choice from options, or fallback when there are none and the snake is boxed in
+func pick(r *rand.Rand, options []int, fallback int) int {
+	if len(options) == 0 {
+		return fallback
+	}
+	return options[r.Intn(len(options))]
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
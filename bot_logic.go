@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"github.com/moneygames-io/gameserver/bot"
+	"math/rand"
+)
+
+// Spawns a bot for every slot no player has taken, call with the lock held
+func (s *State) FillWithBots() {
+	s.Log.Info("Filling %v empty slots with %v bots", s.SignupCount-s.PlayerCount, s.InitialConfig.BotDifficulty)
+	for s.PlayerCount < s.SignupCount {
+		s.SpawnBot()
+	}
+}
+
+func (s *State) SpawnBot() {
+	r := rand.New(rand.NewSource(s.InitialConfig.Seed + int64(s.PlayerCount)))
+	strategy, _ := bot.ForDifficulty(s.InitialConfig.BotDifficulty, r) // Checked by Config.Validate
+
+	newBot := &Player{
+		Name:  fmt.Sprintf("Bot %v", s.PlayerCount+1),
+		Token: fmt.Sprintf("bot:%v:%v", s.GameID, s.PlayerCount),
+		Input: &Input{ZoomLevel: s.InitialConfig.DefaultZoom},
+		Bot:   strategy,
+	}
+
+	s.SpawnPlayer(newBot)
+	s.PlayerCount++
+}
+
+// Lets every live bot decide its input for this tick
+func (s *State) SteerBots() {
+	for id, player := range s.Players {
+		if _, alive := s.Game.ActivePlayers[id]; player.Bot == nil || !alive {
+			continue
+		}
+
+		input := player.Bot.Next(s.Game, id)
+		player.Input.Turns = input.Turns
+		player.Input.Sprinting = input.Sprinting
+	}
+}
+
+// How many snakes still in the game belong to humans
+func (s *State) HumansAlive() int {
+	humans := 0
+	for id := range s.Game.ActivePlayers {
+		if s.Players[id].Bot == nil {
+			humans++
+		}
+	}
+	return humans
+}
+
+// Best placed human whose snake is still alive, bots are never paid out and the dead have been sent Lost
+func (s *State) Winner() *Player {
+	for _, id := range s.Game.Standings() {
+		if _, alive := s.Game.ActivePlayers[id]; alive && s.Players[id].Bot == nil {
+			return s.Players[id]
+		}
+	}
+	return nil
+}
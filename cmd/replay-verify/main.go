@@ -0,0 +1,55 @@
+// Command replay-verify re-runs a replay written by the gameserver and checks the payout
+//
+//	replay-verify 10000.replay.gz
+//
+// Exits 0 when the simulated winner matches the one SendWin paid, 1 when it doesn't
+package main
+
+import (
+	"fmt"
+	"github.com/moneygames-io/gameserver/replay"
+	"os"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: replay-verify <replay file>")
+		os.Exit(2)
+	}
+
+	file, err := os.Open(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer file.Close()
+
+	r, err := replay.Read(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read replay: %v\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("Game:    %v\n", r.GameID)
+	fmt.Printf("Seed:    %v\n", r.Config.Seed)
+	fmt.Printf("Ticks:   %v\n", len(r.Ticks))
+	for _, player := range r.Players {
+		kind := "player"
+		if player.Bot {
+			kind = "bot"
+		}
+		fmt.Printf("Player:  %v %q (%v, %v) spawned at (%v, %v)\n", player.ID, player.Name, player.Hash, kind, player.Row, player.Col)
+	}
+
+	if r.Result != nil {
+		fmt.Printf("Paid:    %v to player %v, standings %v\n", r.Result.Pot, r.Result.Winner, r.Result.Standings)
+	}
+
+	_, err = r.Verify()
+	if err != nil {
+		fmt.Printf("MISMATCH: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("OK: simulated winner matches payout")
+}
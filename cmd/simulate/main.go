@@ -0,0 +1,182 @@
+// Command simulate plays games between bots as fast as the engine allows and prints what happened
+//
+//	simulate -players 16 -games 100 -bots greedy,random -sprint 3
+//
+// Use it to see how ScalingFactor, FoodPerPlayer and SprintFactor change games before shipping a config
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/moneygames-io/gameserver/bot"
+	"github.com/moneygames-io/gameserver/engine"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Totals across every simulated game
+type Stats struct {
+	Games       int
+	Ticks       []int
+	FoodEaten   int
+	Deaths      map[engine.Cause]int
+	Wins        map[string]int
+	Draws       int
+	TimedOut    int
+	WinnerSizes []int
+}
+
+func main() {
+	mode := flag.String("mode", "classic", "rules to play by: "+strings.Join(engine.ModeNames(), ", "))
+	duration := flag.Int("duration", 1260, "DurationTicks, for timed games")
+	zoneInterval := flag.Int("zone-interval", 0, "ZoneInterval, 0 never shrinks the zone")
+	zoneMinSize := flag.Int("zone-min-size", 20, "ZoneMinSize")
+	players := flag.Int("players", 8, "snakes per game")
+	games := flag.Int("games", 10, "how many games to play")
+	bots := flag.String("bots", "greedy,random", "comma separated strategies, assigned to players in turn: "+strings.Join(bot.Names(), ", "))
+	scaling := flag.Int("scaling", 250, "ScalingFactor")
+	food := flag.Int("food", 100, "FoodPerPlayer")
+	sprint := flag.Int("sprint", 2, "SprintFactor")
+	seed := flag.Int64("seed", 0, "seed of the first game, following games add one, 0 picks one from the clock")
+	maxTicks := flag.Int("max-ticks", 100000, "stop a game that runs longer than this")
+	flag.Parse()
+
+	names := strings.Split(*bots, ",")
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	stats := &Stats{
+		Deaths: map[engine.Cause]int{},
+		Wins:   map[string]int{},
+	}
+
+	started := time.Now()
+	for i := 0; i < *games; i++ {
+		config := engine.Config{
+			Mode:          *mode,
+			DurationTicks: *duration,
+			ScalingFactor: *scaling,
+			FoodPerPlayer: *food,
+			SprintFactor:  *sprint,
+			ZoneInterval:  *zoneInterval,
+			ZoneMinSize:   *zoneMinSize,
+			Seed:          *seed + int64(i),
+		}
+
+		err := Play(config, *players, names, *maxTicks, stats)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	stats.Print(time.Since(started))
+}
+
+// Plays one game to completion and adds it to stats
+func Play(config engine.Config, players int, names []string, maxTicks int, stats *Stats) error {
+	if _, err := engine.NewMode(config); err != nil {
+		return err
+	}
+
+	g := engine.New(config, players)
+	r := rand.New(rand.NewSource(config.Seed))
+
+	strategies := map[engine.PlayerID]bot.Strategy{}
+	strategyNames := map[engine.PlayerID]string{}
+	for i := 0; i < players; i++ {
+		name := names[i%len(names)]
+		strategy, err := bot.New(name, r)
+		if err != nil {
+			return err
+		}
+
+		id := g.AddPlayer()
+		strategies[id] = strategy
+		strategyNames[id] = name
+	}
+
+	for !g.Over() && g.Tick < maxTicks {
+		inputs := map[engine.PlayerID]engine.Input{}
+		for _, id := range g.Players {
+			if _, alive := g.ActivePlayers[id]; alive {
+				inputs[id] = strategies[id].Next(g, id)
+			}
+		}
+
+		for _, event := range g.Step(inputs) {
+			switch event.Type {
+			case engine.Ate:
+				stats.FoodEaten++
+			case engine.Died:
+				stats.Deaths[event.Cause]++
+			}
+		}
+	}
+
+	stats.Games++
+	stats.Ticks = append(stats.Ticks, g.Tick)
+
+	if !g.Over() {
+		stats.TimedOut++
+		return nil
+	}
+
+	if len(g.ActivePlayers) == 0 {
+		stats.Draws++
+		return nil
+	}
+
+	winner := g.Standings()[0]
+	stats.Wins[strategyNames[winner]]++
+	stats.WinnerSizes = append(stats.WinnerSizes, g.ActivePlayers[winner].Length)
+	return nil
+}
+
+func (s *Stats) Print(elapsed time.Duration) {
+	fmt.Printf("Games:          %v in %v\n", s.Games, elapsed)
+	fmt.Printf("Game length:    %v ticks\n", summary(s.Ticks))
+	fmt.Printf("Winner length:  %v\n", summary(s.WinnerSizes))
+	fmt.Printf("Food eaten:     %v (%.1f per game)\n", s.FoodEaten, float64(s.FoodEaten)/float64(s.Games))
+
+	fmt.Println("Deaths:")
+	for _, cause := range engine.Causes {
+		fmt.Printf("  %-12v  %v\n", cause, s.Deaths[cause])
+	}
+
+	fmt.Println("Wins:")
+	var strategies []string
+	for name := range s.Wins {
+		strategies = append(strategies, name)
+	}
+	sort.Strings(strategies)
+	for _, name := range strategies {
+		fmt.Printf("  %-12v  %v\n", name, s.Wins[name])
+	}
+	fmt.Printf("  %-12v  %v\n", "draw", s.Draws)
+	fmt.Printf("  %-12v  %v\n", "timed out", s.TimedOut)
+}
+
+// Min, mean and max of values
+func summary(values []int) string {
+	if len(values) == 0 {
+		return "n/a"
+	}
+
+	min, max, total := values[0], values[0], 0
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+		total += v
+	}
+
+	return fmt.Sprintf("min %v, mean %.1f, max %v", min, float64(total)/float64(len(values)), max)
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/moneygames-io/gameserver/bot"
+	"github.com/moneygames-io/gameserver/engine"
+	"github.com/pions/webrtc"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Values used when neither the config file nor the environment set a field
+func DefaultConfig() *Config {
+	return &Config{
+		Mode:             "classic",
+		DurationTicks:    1260,
+		ScalingFactor:    250,
+		FoodPerPlayer:    100,
+		SprintFactor:     2,
+		QueueDepth:       3,
+		ZoneMinSize:      20,
+		LeaderboardSize:  2,
+		FrameRate:        7,
+		DefaultZoom:      10,
+		MinZoom:          5,
+		MaxZoom:          30,
+		ICEServers:       []string{"stun:stun.l.google.com:19302"},
+		ReplayDir:        "replays",
+		MinPlayers:       2,
+		KeyframeInterval: 30,
+		DisconnectGrace:  5,
+		DisconnectPolicy: "kill",
+	}
+}
+
+// Loads the defaults, then the JSON or YAML file named by GSCONFIG, then GS_* environment overrides
+func (s *State) SetupInitialConfig() {
+	config := DefaultConfig()
+
+	path, present := os.LookupEnv("GSCONFIG")
+	if present {
+		err := config.LoadFile(path)
+		if err != nil {
+			s.Log.Fatalf("Could not load config %v: %v", path, err)
+		}
+		s.Log.Info("Loaded config from %v", path)
+	}
+
+	err := config.LoadEnv(os.LookupEnv)
+	if err != nil {
+		s.Log.Fatalf("Could not load config from environment: %v", err)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		s.Log.Fatalf("Invalid config: %v", err)
+	}
+
+	config.BuildRTCSettings()
+	s.InitialConfig = config
+
+	s.Log.Info("Config: %v", config)
+}
+
+// Overlays the fields present in a JSON or YAML file on top of the current values, picked by its extension
+// Both use the json field names
+func (c *Config) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		data, err = yamlToJSON(data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("config file %v isn't .json, .yaml or .yml", path)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(c)
+}
+
+// Re-encodes a YAML mapping as JSON so both formats go through the same strict decoding
+// Config has no nested objects, so only the top level needs string keys
+func yamlToJSON(data []byte) ([]byte, error) {
+	fields := map[string]interface{}{}
+	err := yaml.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// Overlays any GS_* variables returned by lookup on top of the current values
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	err := c.overlay(lookup, map[string]*string{
+		"GS_MODE":              &c.Mode,
+		"GS_REPLAY_DIR":        &c.ReplayDir,
+		"GS_BOT_DIFFICULTY":    &c.BotDifficulty,
+		"GS_DISCONNECT_POLICY": &c.DisconnectPolicy,
+	}, map[string]*int{
+		"GS_DURATION_TICKS":    &c.DurationTicks,
+		"GS_SCALING_FACTOR":    &c.ScalingFactor,
+		"GS_FOOD_PER_PLAYER":   &c.FoodPerPlayer,
+		"GS_SPRINT_FACTOR":     &c.SprintFactor,
+		"GS_QUEUE_DEPTH":       &c.QueueDepth,
+		"GS_ZONE_INTERVAL":     &c.ZoneInterval,
+		"GS_ZONE_MIN_SIZE":     &c.ZoneMinSize,
+		"GS_LEADERBOARD_SIZE":  &c.LeaderboardSize,
+		"GS_FRAME_RATE":        &c.FrameRate,
+		"GS_DEFAULT_ZOOM":      &c.DefaultZoom,
+		"GS_MIN_ZOOM":          &c.MinZoom,
+		"GS_MAX_ZOOM":          &c.MaxZoom,
+		"GS_JOIN_TIMEOUT":      &c.JoinTimeout,
+		"GS_MIN_PLAYERS":       &c.MinPlayers,
+		"GS_DISCONNECT_GRACE":  &c.DisconnectGrace,
+		"GS_KEYFRAME_INTERVAL": &c.KeyframeInterval,
+	})
+	if err != nil {
+		return err
+	}
+
+	servers, present := lookup("GS_ICE_SERVERS")
+	if present {
+		c.ICEServers = strings.Split(servers, ",")
+	}
+
+	seed, present := lookup("GS_SEED")
+	if present {
+		c.Seed, err = strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			return fmt.Errorf("GS_SEED: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Overlays the game profile the matchmaker wrote into this game's hash
+// Only the per-lobby fields can be set this way, the rest stay server wide
+func (c *Config) LoadProfile(profile map[string]string) error {
+	lookup := func(name string) (string, bool) {
+		value, present := profile[name]
+		return value, present
+	}
+
+	return c.overlay(lookup, map[string]*string{
+		"mode":           &c.Mode,
+		"bot_difficulty": &c.BotDifficulty,
+	}, map[string]*int{
+		"duration_ticks":   &c.DurationTicks,
+		"scaling_factor":   &c.ScalingFactor,
+		"food_per_player":  &c.FoodPerPlayer,
+		"frame_rate":       &c.FrameRate,
+		"leaderboard_size": &c.LeaderboardSize,
+		"zone_interval":    &c.ZoneInterval,
+		"zone_min_size":    &c.ZoneMinSize,
+		"join_timeout":     &c.JoinTimeout,
+		"min_players":      &c.MinPlayers,
+	})
+}
+
+func (c *Config) overlay(lookup func(string) (string, bool), strs map[string]*string, ints map[string]*int) error {
+	for name, field := range strs {
+		value, present := lookup(name)
+		if present {
+			*field = value
+		}
+	}
+
+	for name, field := range ints {
+		value, present := lookup(name)
+		if !present {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+		*field = parsed
+	}
+
+	return nil
+}
+
+// Rejects values the game loop can't run with
+func (c *Config) Validate() error {
+	if _, err := engine.NewMode(c.EngineConfig()); err != nil {
+		return err
+	}
+
+	if c.ScalingFactor < 1 {
+		return errors.New("scaling_factor must be at least 1")
+	}
+
+	if c.FoodPerPlayer < 0 {
+		return errors.New("food_per_player can't be negative")
+	}
+
+	if c.SprintFactor < 1 {
+		return errors.New("sprint_factor must be at least 1")
+	}
+
+	if c.QueueDepth < 0 { // 0 doesn't limit the queue, as in engine.Config
+		return errors.New("queue_depth can't be negative")
+	}
+
+	if c.ZoneInterval < 0 {
+		return errors.New("zone_interval can't be negative")
+	}
+
+	if c.ZoneMinSize < 1 {
+		return errors.New("zone_min_size must be at least 1")
+	}
+
+	if c.LeaderboardSize < 1 {
+		return errors.New("leaderboard_size must be at least 1")
+	}
+
+	if c.FrameRate < 1 { // FrameUpdater divides by this
+		return errors.New("frame_rate must be at least 1")
+	}
+
+	if c.MinZoom < 1 {
+		return errors.New("min_zoom must be at least 1")
+	}
+
+	if c.MaxZoom > 255 { // Clients send zoom as a byte
+		return errors.New("max_zoom can't be more than 255")
+	}
+
+	if c.DefaultZoom < c.MinZoom || c.DefaultZoom > c.MaxZoom {
+		return errors.New("default_zoom must be between min_zoom and max_zoom")
+	}
+
+	if c.ReplayDir == "" {
+		return errors.New("replay_dir must be set")
+	}
+
+	if c.JoinTimeout < 0 {
+		return errors.New("join_timeout can't be negative")
+	}
+
+	if c.MinPlayers < 2 { // One player would be paid their own pot
+		return errors.New("min_players must be at least 2")
+	}
+
+	if c.KeyframeInterval < 1 {
+		return errors.New("keyframe_interval must be at least 1")
+	}
+
+	if c.DisconnectGrace < 0 {
+		return errors.New("disconnect_grace can't be negative")
+	}
+
+	if c.DisconnectPolicy != "kill" && c.DisconnectPolicy != "straight" {
+		return fmt.Errorf("unknown disconnect_policy %q", c.DisconnectPolicy)
+	}
+
+	if c.BotDifficulty != "" {
+		_, err := bot.ForDifficulty(c.BotDifficulty, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Merges the matchmaker's profile for this game over the server config
+// A bad profile is logged and ignored so the lobby still runs on the defaults
+func (s *State) ApplyGameProfile() {
+	profile, err := s.GameserverRedis.HGetAll(s.GameID).Result()
+	if err != nil {
+		s.Log.Error("Could not read game profile: %v", err)
+		return
+	}
+
+	config := *s.InitialConfig
+	err = config.LoadProfile(profile)
+	if err == nil {
+		err = config.Validate()
+	}
+
+	if err != nil {
+		s.Log.Error("Ignoring game profile: %v", err)
+		return
+	}
+
+	s.InitialConfig = &config
+	s.Log.Info("Game profile applied: %v", s.InitialConfig)
+}
+
+// Subset of the config the engine's rules need
+func (c *Config) EngineConfig() engine.Config {
+	return engine.Config{
+		Mode:          c.Mode,
+		DurationTicks: c.DurationTicks,
+		ScalingFactor: c.ScalingFactor,
+		FoodPerPlayer: c.FoodPerPlayer,
+		SprintFactor:  c.SprintFactor,
+		QueueDepth:    c.QueueDepth,
+		ZoneInterval:  c.ZoneInterval,
+		ZoneMinSize:   c.ZoneMinSize,
+		Seed:          c.Seed,
+	}
+}
+
+func (c *Config) BuildRTCSettings() {
+	c.RTCSettings = webrtc.RTCConfiguration{
+		IceServers: []webrtc.RTCIceServer{
+			{
+				URLs: c.ICEServers,
+			},
+		},
+	}
+}
+
+func (c *Config) String() string {
+	encoded, _ := json.Marshal(c)
+	return string(encoded)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_LoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+		return path
+	}
+
+	c := DefaultConfig()
+	assert.Nil(t, c.LoadFile(write("config.json", `{"frame_rate": 12}`)))
+	assert.Equal(t, 12, c.FrameRate)
+
+	assert.Nil(t, c.LoadFile(write("config.yaml", "frame_rate: 9\nice_servers:\n  - stun:a:1\n")))
+	assert.Equal(t, 9, c.FrameRate)
+	assert.Equal(t, []string{"stun:a:1"}, c.ICEServers)
+	assert.Equal(t, 250, c.ScalingFactor)
+
+	assert.NotNil(t, c.LoadFile(write("unknown.yml", "frames: 9\n")))
+	assert.NotNil(t, c.LoadFile(write("config.toml", "frame_rate = 9\n")))
+}
+
+func TestConfig_LoadEnv(t *testing.T) {
+	env := map[string]string{
+		"GS_FRAME_RATE":  "12",
+		"GS_ICE_SERVERS": "stun:a:1,stun:b:2",
+	}
+	lookup := func(name string) (string, bool) {
+		value, present := env[name]
+		return value, present
+	}
+
+	c := DefaultConfig()
+	assert.Nil(t, c.LoadEnv(lookup))
+	assert.Equal(t, 12, c.FrameRate)
+	assert.Equal(t, 250, c.ScalingFactor)
+	assert.Equal(t, []string{"stun:a:1", "stun:b:2"}, c.ICEServers)
+
+	env["GS_FRAME_RATE"] = "fast"
+	assert.NotNil(t, c.LoadEnv(lookup))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := DefaultConfig()
+	assert.Nil(t, c.Validate())
+
+	c.FrameRate = 0
+	assert.NotNil(t, c.Validate())
+
+	c = DefaultConfig()
+	c.DefaultZoom = c.MaxZoom + 1
+	assert.NotNil(t, c.Validate())
+
+	c = DefaultConfig()
+	c.MinPlayers = 1
+	assert.NotNil(t, c.Validate())
+
+	c = DefaultConfig()
+	c.QueueDepth = 0
+	assert.Nil(t, c.Validate())
+	c.QueueDepth = -1
+	assert.NotNil(t, c.Validate())
+}
+
+func TestConfig_LoadProfile(t *testing.T) {
+	c := DefaultConfig()
+	assert.Nil(t, c.LoadProfile(map[string]string{
+		"players":        "4",
+		"pot":            "1000",
+		"mode":           "classic",
+		"scaling_factor": "50",
+		"frame_rate":     "10",
+	}))
+	assert.Equal(t, 50, c.ScalingFactor)
+	assert.Equal(t, 10, c.FrameRate)
+	assert.Equal(t, 100, c.FoodPerPlayer)
+
+	assert.Nil(t, c.LoadProfile(map[string]string{"mode": "unknown"}))
+	assert.NotNil(t, c.Validate())
+}
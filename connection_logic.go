@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/pions/webrtc/pkg/ice"
+	"time"
+)
+
+// Tracks whether a player's transport is usable, ICE can recover from disconnected on its own
+// Changes on a transport the player has since replaced by reconnecting are ignored
+func (s *State) PlayerConnectionStateChanged(player *Player, t Transport, connected bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if player.Connection != t {
+		return
+	}
+
+	switch {
+	case !connected && player.Connected:
+		s.Log.Info("%v disconnected, %v seconds to come back", player.Name, s.InitialConfig.DisconnectGrace)
+		player.Connected = false
+		player.DisconnectedAt = time.Now()
+	case connected && !player.Connected:
+		s.Log.Info("%v reconnected", player.Name)
+		player.Connected = true
+	}
+}
+
+// Whether an ICE state means the peer connection can carry messages, and whether it says anything at all
+func iceConnected(state ice.ConnectionState) (connected bool, known bool) {
+	switch state {
+	case ice.ConnectionStateDisconnected, ice.ConnectionStateFailed, ice.ConnectionStateClosed:
+		return false, true
+	case ice.ConnectionStateConnected, ice.ConnectionStateCompleted:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// Applies DisconnectPolicy to players who have been gone longer than DisconnectGrace
+// Until then their snake carries on with the last input they sent
+func (s *State) ApplyDisconnectPolicy() {
+	grace := time.Duration(s.InitialConfig.DisconnectGrace) * time.Second
+
+	for id, player := range s.Players {
+		if _, alive := s.Game.ActivePlayers[id]; !alive || player.Bot != nil || player.Connected {
+			continue
+		}
+
+		if time.Since(player.DisconnectedAt) < grace {
+			continue
+		}
+
+		switch s.InitialConfig.DisconnectPolicy {
+		case "kill":
+			s.Log.Info("%v didn't come back, forfeiting their snake", player.Name)
+			player.Forfeit = true
+		case "straight":
+			player.Input.Sprinting = false
+			player.Input.Turns = nil
+			player.Input.Direction = s.Game.Headings[id]
+			delete(s.Game.Queues, id) // Turns the engine still had queued from before the drop
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// A lobby of three with two players in, the game loop isn't running so tests step it themselves
+func disconnectLobby(t *testing.T, policy string) (*State, []*Player, func()) {
+	s, done := newTestState(t, 3)
+	s.InitialConfig.DisconnectPolicy = policy
+	s.InitialConfig.DisconnectGrace = 5
+
+	lobby(t, s, []string{"a", "b", "c"}, 2)
+	return s, []*Player{s.Players[0], s.Players[1]}, done
+}
+
+func TestApplyDisconnectPolicy_Grace(t *testing.T) {
+	s, players, done := disconnectLobby(t, "kill")
+	defer done()
+
+	s.PlayerConnectionStateChanged(players[0], players[0].Connection, false)
+	assert.False(t, players[0].Connected)
+
+	s.ApplyDisconnectPolicy()
+	assert.False(t, players[0].Forfeit)
+
+	// Coming back inside the grace period leaves the snake alone
+	s.PlayerConnectionStateChanged(players[0], players[0].Connection, true)
+	players[0].DisconnectedAt = time.Now().Add(-time.Minute)
+	s.ApplyDisconnectPolicy()
+	assert.False(t, players[0].Forfeit)
+}
+
+func TestApplyDisconnectPolicy_Kill(t *testing.T) {
+	s, players, done := disconnectLobby(t, "kill")
+	defer done()
+
+	s.PlayerConnectionStateChanged(players[0], players[0].Connection, false)
+	players[0].DisconnectedAt = time.Now().Add(-6 * time.Second)
+
+	s.MoveSnakesForward()
+	assert.True(t, players[0].Forfeit)
+	assert.NotContains(t, s.Game.ActivePlayers, players[0].ID)
+	assert.Contains(t, s.Game.ActivePlayers, players[1].ID)
+	assert.Equal(t, "lost", status(s, "a"))
+}
+
+func TestApplyDisconnectPolicy_Straight(t *testing.T) {
+	s, players, done := disconnectLobby(t, "straight")
+	defer done()
+
+	player := players[0]
+	heading := s.Game.Headings[player.ID]
+	player.Input.Direction = (heading + 1) % 4
+	player.Input.Turns = []int{player.Input.Direction}
+	player.Input.Sprinting = true
+	s.Game.Queues[player.ID] = []int{heading, player.Input.Direction}
+
+	s.PlayerConnectionStateChanged(player, player.Connection, false)
+	player.DisconnectedAt = time.Now().Add(-6 * time.Second)
+
+	for i := 0; i < 3; i++ {
+		s.MoveSnakesForward()
+		assert.False(t, player.Forfeit)
+		assert.False(t, player.Input.Sprinting)
+		assert.Empty(t, player.Input.Turns)
+		assert.Empty(t, s.Game.Queues[player.ID])
+		assert.Equal(t, heading, s.Game.Headings[player.ID])
+		assert.Equal(t, heading, player.Input.Direction)
+	}
+	assert.Equal(t, 3, s.Game.Tick)
+}
@@ -0,0 +1,24 @@
+package engine
+
+func init() {
+	RegisterMode("classic", func(config Config) (GameMode, error) {
+		return Classic{}, nil
+	})
+}
+
+// Last snake alive wins, everyone else places in the reverse of the order they died
+type Classic struct{}
+
+func (Classic) Spawned(g *Game, id PlayerID) {}
+
+func (Classic) Tick(g *Game) {}
+
+func (Classic) Died(g *Game, event Event) {}
+
+func (Classic) Over(g *Game) bool {
+	return len(g.ActivePlayers) <= 1
+}
+
+func (Classic) Standings(g *Game) []PlayerID {
+	return g.SurvivalStandings()
+}
@@ -0,0 +1,12 @@
+package engine
+
+func (g *Game) SpawnFoodAtRandomLocation(howMuch int) {
+	for i := 0; i < howMuch; i++ {
+		row, col := g.FindRandomEmptyLocation()
+		g.SpawnFoodAtLocation(row, col)
+	}
+}
+
+func (g *Game) SpawnFoodAtLocation(row, col int) {
+	g.Tiles[row][col] = &FoodNode{row, col}
+}
@@ -0,0 +1,305 @@
+// Package engine holds the rules of the game with no I/O
+// Transports, Redis and logging live in the server, which drives a Game through Step
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Root of a game's object graph, everything the rules read and write
+type Game struct {
+	Config Config
+
+	// Rules picked by Config.Mode
+	Mode GameMode
+
+	// 2D world which all the game logic operates on
+	Tiles [][]Object
+
+	// Where on Tiles snakes can go, the whole map until it starts shrinking
+	Zone Zone
+
+	// Snakes still alive, keyed by their head
+	ActivePlayers map[PlayerID]*SnakeNode
+
+	// Snakes that have died, keyed by the head they died with
+	LostPlayers map[PlayerID]*SnakeNode
+
+	// Players in the order they died
+	Eliminated []PlayerID
+
+	// Tick each dead player died on
+	DiedAt map[PlayerID]int
+
+	// Every player in join order, the order events within a step come out in
+	Players []PlayerID
+
+	// Direction each snake last moved in, Up until it has moved
+	Headings map[PlayerID]int
+
+	// Turns each snake is yet to make, the next one is taken on its next move
+	Queues map[PlayerID][]int
+
+	// How many times Step has run
+	Tick int
+
+	// Source for spawn locations, seeded from Config.Seed
+	rand *rand.Rand
+
+	// Collected while a Step runs and handed back when it returns
+	events []Event
+}
+
+type EventType int
+
+const (
+	// A snake hit something and was removed from ActivePlayers
+	Died EventType = iota
+
+	// A snake moved onto food and grew
+	Ate
+)
+
+// What a snake ran into when it Died
+type Cause int
+
+const (
+	NoCause Cause = iota
+	HitWall
+
+	// Its own body
+	HitSelf
+
+	// Another snake's body, that snake gets the kill
+	HitBody
+
+	Forfeited
+
+	// Met another head, swapping places or going for the same tile, and wasn't the longer snake
+	// The longer snake gets the kill, nobody does when it's a tie
+	HeadOn
+)
+
+// Every Cause a snake can die of
+var Causes = []Cause{HitWall, HitSelf, HitBody, Forfeited, HeadOn}
+
+// Stands in for a player where there isn't one, like the killer of a snake that hit a wall
+const NoPlayer PlayerID = -1
+
+func (c Cause) String() string {
+	switch c {
+	case HitWall:
+		return "wall"
+	case HitSelf:
+		return "self"
+	case HitBody:
+		return "body"
+	case Forfeited:
+		return "forfeit"
+	case HeadOn:
+		return "head-on"
+	default:
+		return "none"
+	}
+}
+
+// Something that happened during a Step that the server may need to act on
+type Event struct {
+	Type   EventType
+	Player PlayerID
+	Tick   int
+	Cause  Cause
+
+	// Who gets the credit when Player Died, NoPlayer if nobody does
+	Killer PlayerID
+}
+
+// Creates the map based on how many players are destined to join and scaling factor
+// Config.Mode has to be one of ModeNames, check it with NewMode first
+func New(config Config, signupCount int) *Game {
+	mode, err := NewMode(config)
+	if err != nil {
+		panic(err)
+	}
+
+	g := &Game{
+		Mode:          mode,
+		Config:        config,
+		ActivePlayers: map[PlayerID]*SnakeNode{},
+		LostPlayers:   map[PlayerID]*SnakeNode{},
+		DiedAt:        map[PlayerID]int{},
+		Headings:      map[PlayerID]int{},
+		Queues:        map[PlayerID][]int{},
+		rand:          rand.New(rand.NewSource(config.Seed)),
+	}
+
+	// Compute Map Bounds
+	mapSize := int(math.Sqrt(float64(signupCount))) * config.ScalingFactor
+
+	// Initialize Map
+	g.Tiles = make([][]Object, mapSize)
+	for i := range g.Tiles {
+		g.Tiles[i] = make([]Object, mapSize)
+	}
+	g.Zone = Zone{Size: mapSize}
+
+	return g
+}
+
+// Spawns a snake for a new player along with their share of food
+func (g *Game) AddPlayer() PlayerID {
+	id := PlayerID(len(g.Players))
+	g.Players = append(g.Players, id)
+
+	snake := &SnakeNode{Player: id}
+	g.ActivePlayers[id] = snake
+	g.AddNewSnakeToWorld(snake)
+	g.Mode.Spawned(g, id)
+
+	return id
+}
+
+// Advances the game by one tick, moving every live snake by its player's input
+// A tick is SprintFactor rounds of simultaneous moves, everyone moves in the first and sprinters in all of them
+func (g *Game) Step(inputs map[PlayerID]Input) []Event {
+	g.Tick++
+	g.events = nil
+	g.shrinkZone()
+	g.Mode.Tick(g)
+
+	for _, id := range g.Players {
+		snake, alive := g.ActivePlayers[id]
+		if alive && inputs[id].Forfeit {
+			g.Dead(snake, Forfeited, NoPlayer)
+		}
+	}
+
+	for _, id := range g.Players {
+		if _, alive := g.ActivePlayers[id]; alive {
+			g.Queue(id, inputs[id].Turns)
+		}
+	}
+
+	for round := 0; round < g.Config.SprintFactor || round == 0; round++ {
+		var movers []PlayerID
+		for _, id := range g.Players {
+			if round == 0 || inputs[id].Sprinting {
+				movers = append(movers, id)
+			}
+		}
+		g.Advance(movers)
+	}
+
+	return g.events
+}
+
+// Adds turns to the end of a player's queue, skipping ones that wouldn't change direction
+func (g *Game) Queue(id PlayerID, turns []int) {
+	for _, turn := range turns {
+		queue := g.Queues[id]
+
+		last := g.Headings[id]
+		if len(queue) > 0 {
+			last = queue[len(queue)-1]
+		}
+
+		if turn == last || (g.Config.QueueDepth > 0 && len(queue) >= g.Config.QueueDepth) {
+			continue
+		}
+		g.Queues[id] = append(queue, turn)
+	}
+}
+
+// Takes the player's next turn off their queue if there is one, and returns the direction to move in
+// A snake with a neck can't turn back into it, such turns are thrown away and the next one is tried
+func (g *Game) NextDirection(id PlayerID) int {
+	snake := g.ActivePlayers[id]
+	for len(g.Queues[id]) > 0 {
+		turn := g.Queues[id][0]
+		g.Queues[id] = g.Queues[id][1:]
+
+		if snake != nil && snake.Next != nil && turn == Opposite(g.Headings[id]) {
+			continue
+		}
+
+		g.Headings[id] = turn
+		break
+	}
+	return g.Headings[id]
+}
+
+func (g *Game) emit(eventType EventType, player PlayerID, cause Cause, killer PlayerID) {
+	g.events = append(g.events, Event{
+		Type:   eventType,
+		Player: player,
+		Tick:   g.Tick,
+		Cause:  cause,
+		Killer: killer,
+	})
+}
+
+// Players still alive, longest snake first
+func (g *Game) Rankings() []PlayerID {
+	players := make([]PlayerID, 0, len(g.ActivePlayers))
+	for _, id := range g.Players {
+		if _, alive := g.ActivePlayers[id]; alive {
+			players = append(players, id)
+		}
+	}
+
+	sort.SliceStable(players, func(i, j int) bool {
+		return g.ActivePlayers[players[i]].Length > g.ActivePlayers[players[j]].Length
+	})
+
+	return players
+}
+
+// Whether the mode says the game is finished
+func (g *Game) Over() bool {
+	return g.Mode.Over(g)
+}
+
+// Ticks until the mode ends the game on time, -1 if it doesn't
+func (g *Game) TicksLeft() int {
+	if clock, timed := g.Mode.(Clock); timed {
+		return clock.TicksLeft(g)
+	}
+	return -1
+}
+
+// Every player best first by the mode's rules, the order they're paid out in
+func (g *Game) Standings() []PlayerID {
+	return g.Mode.Standings(g)
+}
+
+// Every player best first: the live ones by Rankings, then the dead ones from last to die to first
+// Snakes that died on the same tick died together, the longer placed first and then the earlier to join
+func (g *Game) SurvivalStandings() []PlayerID {
+	dead := append([]PlayerID{}, g.Eliminated...)
+	sort.Slice(dead, func(i, j int) bool {
+		a, b := dead[i], dead[j]
+		if g.DiedAt[a] != g.DiedAt[b] {
+			return g.DiedAt[a] > g.DiedAt[b]
+		}
+		if g.LostPlayers[a].Length != g.LostPlayers[b].Length {
+			return g.LostPlayers[a].Length > g.LostPlayers[b].Length
+		}
+		return a < b // Ids are handed out in join order
+	})
+
+	return append(g.Rankings(), dead...)
+}
+
+// Picks a tile inside the zone with nothing on it
+func (g *Game) FindRandomEmptyLocation() (int, int) {
+	row := g.Zone.Row + g.rand.Intn(g.Zone.Size)
+	col := g.Zone.Col + g.rand.Intn(g.Zone.Size)
+
+	if g.Get(&Coordinate{row, col}) != nil {
+		return g.FindRandomEmptyLocation()
+	}
+
+	return row, col
+}
@@ -0,0 +1,273 @@
+package engine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	g := New(Config{ScalingFactor: 100}, 100)
+
+	assert.Equal(t, len(g.Tiles), 1000)
+	for _, row := range g.Tiles {
+		assert.Equal(t, len(row), 1000)
+	}
+}
+
+func TestGame_FindRandomEmptyLocation(t *testing.T) {
+	g := New(Config{ScalingFactor: 4}, 4)
+
+	assert.Equal(t, 8, len(g.Tiles))
+	for _, row := range g.Tiles {
+		assert.Equal(t, 8, len(row))
+	}
+
+	row, col := g.FindRandomEmptyLocation()
+	assert.Nil(t, g.Get(&Coordinate{row, col}))
+}
+
+func TestNew_Seed(t *testing.T) {
+	config := Config{ScalingFactor: 10, FoodPerPlayer: 5, Seed: 42}
+	a := New(config, 4)
+	b := New(config, 4)
+
+	for i := 0; i < 4; i++ {
+		a.AddPlayer()
+		b.AddPlayer()
+	}
+
+	assert.Equal(t, a.Tiles, b.Tiles)
+}
+
+func TestGame_Step(t *testing.T) {
+	g := New(Config{ScalingFactor: 10, SprintFactor: 2}, 1)
+	a := g.AddPlayer()
+
+	// Place the snake by hand so the test doesn't depend on the RNG
+	head := g.ActivePlayers[a]
+	g.Tiles[head.Row][head.Col] = nil
+	head.Row, head.Col = 5, 5
+	g.Tiles[5][5] = head
+	g.Tiles[4][5] = nil
+	g.SpawnFoodAtLocation(4, 5)
+
+	events := g.Step(map[PlayerID]Input{a: {Turns: []int{Up}}})
+	assert.Equal(t, []Event{{Type: Ate, Player: a, Tick: 1, Killer: NoPlayer}}, events)
+	assert.Equal(t, 2, g.ActivePlayers[a].Length)
+	assert.Equal(t, 4, g.ActivePlayers[a].Row)
+
+	g.Tiles[2][5] = nil
+	g.Tiles[3][5] = nil
+	events = g.Step(map[PlayerID]Input{a: {Turns: []int{Up}, Sprinting: true}})
+	assert.Empty(t, events)
+	assert.Equal(t, 2, g.ActivePlayers[a].Row)
+	assert.Nil(t, g.Tiles[4][5])
+
+	for i := 0; i < 2; i++ {
+		g.Tiles[i][5] = nil
+	}
+	g.Step(map[PlayerID]Input{a: {Turns: []int{Up}}})
+	g.Step(map[PlayerID]Input{a: {Turns: []int{Up}}})
+	events = g.Step(map[PlayerID]Input{a: {Turns: []int{Up}}})
+	assert.Equal(t, []Event{{Type: Died, Player: a, Tick: 5, Cause: HitWall, Killer: NoPlayer}}, events)
+	assert.Empty(t, g.ActivePlayers)
+	assert.NotNil(t, g.LostPlayers[a])
+	assert.Equal(t, []PlayerID{a}, g.Standings())
+}
+
+func TestGame_Step_Forfeit(t *testing.T) {
+	g := New(Config{ScalingFactor: 10, SprintFactor: 2}, 4)
+	a := g.AddPlayer()
+	b := g.AddPlayer()
+
+	events := g.Step(map[PlayerID]Input{b: {Forfeit: true}})
+	assert.Contains(t, events, Event{Type: Died, Player: b, Tick: 1, Cause: Forfeited, Killer: NoPlayer})
+	assert.Contains(t, g.ActivePlayers, a)
+	assert.NotContains(t, g.ActivePlayers, b)
+}
+
+// Moves a freshly spawned single node snake to row, col
+func place(g *Game, id PlayerID, row, col int) *SnakeNode {
+	head := g.ActivePlayers[id]
+	g.Tiles[head.Row][head.Col] = nil
+	head.Row, head.Col = row, col
+	g.Tiles[row][col] = head
+	return head
+}
+
+func TestGame_Step_Turns(t *testing.T) {
+	g := New(Config{ScalingFactor: 10, SprintFactor: 2, QueueDepth: 2}, 1)
+	a := g.AddPlayer()
+	place(g, a, 5, 5)
+
+	// Both turns are kept, one is taken per move
+	g.Step(map[PlayerID]Input{a: {Turns: []int{Left, Down}}})
+	assert.Equal(t, Coordinate{Row: 5, Col: 4}, Coordinate{Row: g.ActivePlayers[a].Row, Col: g.ActivePlayers[a].Col})
+	g.Step(map[PlayerID]Input{a: {}})
+	assert.Equal(t, Coordinate{Row: 6, Col: 4}, Coordinate{Row: g.ActivePlayers[a].Row, Col: g.ActivePlayers[a].Col})
+	assert.Equal(t, Down, g.Headings[a])
+
+	// Every step of a sprint takes a turn
+	g.Step(map[PlayerID]Input{a: {Turns: []int{Right, Down}, Sprinting: true}})
+	assert.Equal(t, Coordinate{Row: 7, Col: 5}, Coordinate{Row: g.ActivePlayers[a].Row, Col: g.ActivePlayers[a].Col})
+
+	// Turns past QueueDepth are dropped, repeats of the last direction don't use up space
+	g.Queue(a, []int{Down, Left, Left, Up, Right})
+	assert.Equal(t, []int{Left, Up}, g.Queues[a])
+}
+
+// A two node snake with its head at row, col facing Up
+func grow(g *Game, id PlayerID, row, col int) {
+	place(g, id, row+1, col)
+	g.SpawnFoodAtLocation(row, col)
+	g.Step(map[PlayerID]Input{id: {Turns: []int{Up}}})
+}
+
+func TestGame_Step_Reversal(t *testing.T) {
+	g := New(Config{ScalingFactor: 10, SprintFactor: 2}, 1)
+	a := g.AddPlayer()
+	grow(g, a, 5, 5)
+	assert.Equal(t, 2, g.ActivePlayers[a].Length)
+
+	// Turning back into the neck is ignored and the snake carries on
+	events := g.Step(map[PlayerID]Input{a: {Turns: []int{Down}}})
+	assert.Empty(t, events)
+	assert.Equal(t, 4, g.ActivePlayers[a].Row)
+	assert.Equal(t, Up, g.Headings[a])
+
+	// The turn after an ignored reversal still applies on the same move
+	g.Step(map[PlayerID]Input{a: {Turns: []int{Down, Left}}})
+	assert.Equal(t, Coordinate{Row: 4, Col: 4}, Coordinate{Row: g.ActivePlayers[a].Row, Col: g.ActivePlayers[a].Col})
+
+	// Sprinting is checked move by move: Right would reverse on the first step but not after Up
+	events = g.Step(map[PlayerID]Input{a: {Turns: []int{Right, Up, Right}, Sprinting: true}})
+	assert.Empty(t, events)
+	assert.Equal(t, Coordinate{Row: 3, Col: 5}, Coordinate{Row: g.ActivePlayers[a].Row, Col: g.ActivePlayers[a].Col})
+}
+
+func TestGame_Step_ReversalSingleNode(t *testing.T) {
+	g := New(Config{ScalingFactor: 10, SprintFactor: 2}, 1)
+	a := g.AddPlayer()
+	place(g, a, 5, 5)
+
+	// Nothing to run into, a lone head can turn straight around
+	g.Step(map[PlayerID]Input{a: {Turns: []int{Up}}})
+	events := g.Step(map[PlayerID]Input{a: {Turns: []int{Down}}})
+	assert.Empty(t, events)
+	assert.Equal(t, 5, g.ActivePlayers[a].Row)
+}
+
+// Counts the hooks the engine calls and ends the game after a set tick
+type countingMode struct {
+	Classic
+	spawned, ticks int
+	died           []Event
+	lastTick       int
+}
+
+func (m *countingMode) Spawned(g *Game, id PlayerID) { m.spawned++ }
+func (m *countingMode) Tick(g *Game)                 { m.ticks++ }
+func (m *countingMode) Died(g *Game, event Event)    { m.died = append(m.died, event) }
+func (m *countingMode) Over(g *Game) bool            { return g.Tick >= m.lastTick }
+
+func TestGame_Mode(t *testing.T) {
+	_, err := NewMode(Config{Mode: "unknown"})
+	assert.NotNil(t, err)
+	assert.Contains(t, ModeNames(), "classic")
+
+	g := New(Config{ScalingFactor: 10}, 2)
+	assert.IsType(t, Classic{}, g.Mode)
+
+	mode := &countingMode{lastTick: 2}
+	g.Mode = mode
+	a := g.AddPlayer()
+	g.AddPlayer()
+	assert.Equal(t, 2, mode.spawned)
+
+	g.Step(map[PlayerID]Input{a: {Forfeit: true}})
+	assert.Equal(t, 1, mode.ticks)
+	assert.Equal(t, []Event{{Type: Died, Player: a, Tick: 1, Cause: Forfeited, Killer: NoPlayer}}, mode.died)
+	assert.False(t, g.Over())
+
+	g.Step(nil)
+	assert.True(t, g.Over())
+}
+
+func TestTimed(t *testing.T) {
+	g := New(Config{Mode: "timed", DurationTicks: 3, ScalingFactor: 10}, 1)
+	a, b, c := g.AddPlayer(), g.AddPlayer(), g.AddPlayer()
+	lay(g, a, Right, Coordinate{5, 2})
+	lay(g, b, Right, Coordinate{2, 2})
+	lay(g, c, Right, Coordinate{8, 2})
+
+	// b and a end up the same length but a gets there a tick later
+	g.SpawnFoodAtLocation(2, 3)
+	g.SpawnFoodAtLocation(5, 4)
+	assert.Equal(t, 3, g.TicksLeft())
+
+	right := map[PlayerID]Input{a: {Turns: []int{Right}}, b: {Turns: []int{Right}}, c: {Turns: []int{Right}}}
+	g.Step(right)
+	g.Step(right)
+	assert.False(t, g.Over())
+	assert.Equal(t, 1, g.TicksLeft())
+
+	g.Step(right)
+	assert.True(t, g.Over())
+	assert.Equal(t, 0, g.TicksLeft())
+	assert.Equal(t, []PlayerID{b, a, c}, g.Standings())
+
+	_, err := NewMode(Config{Mode: "timed"})
+	assert.NotNil(t, err)
+	assert.Equal(t, -1, New(Config{ScalingFactor: 10}, 1).TicksLeft())
+}
+
+func TestGame_SurvivalStandings(t *testing.T) {
+	g := New(Config{ScalingFactor: 10}, 1)
+	a, b, c, d := g.AddPlayer(), g.AddPlayer(), g.AddPlayer(), g.AddPlayer()
+	lay(g, a, Right, Coordinate{2, 2})
+	lay(g, b, Right, Coordinate{4, 2}, Coordinate{4, 1})
+	lay(g, c, Right, Coordinate{6, 2})
+	lay(g, d, Right, Coordinate{8, 2})
+
+	g.Step(map[PlayerID]Input{d: {Forfeit: true}})
+	assert.Equal(t, []PlayerID{b, a, c, d}, g.Standings())
+
+	// The last three go together, the longest is placed first and the others by join order, not by who died last
+	g.Step(map[PlayerID]Input{c: {Forfeit: true}, b: {Forfeit: true}, a: {Forfeit: true}})
+	assert.True(t, g.Over())
+	assert.Empty(t, g.ActivePlayers)
+	assert.Equal(t, []PlayerID{b, a, c, d}, g.Standings())
+}
+
+func TestGame_Zone(t *testing.T) {
+	g := New(Config{ScalingFactor: 10, ZoneInterval: 2, ZoneMinSize: 6}, 1)
+	a, b := g.AddPlayer(), g.AddPlayer()
+	lay(g, a, Up, Coordinate{3, 5})
+	lay(g, b, Left, Coordinate{0, 3})
+	g.SpawnFoodAtLocation(0, 0)
+	assert.Equal(t, Zone{Size: 10}, g.Zone)
+
+	events := g.Step(map[PlayerID]Input{a: {Turns: []int{Up}}, b: {Turns: []int{Left}}})
+	assert.Empty(t, events)
+
+	// b's head is left outside by the shrink, it dies before it can turn back in
+	events = g.Step(map[PlayerID]Input{a: {Turns: []int{Up}}, b: {Turns: []int{Down}}})
+	assert.Equal(t, Zone{Row: 1, Col: 1, Size: 8}, g.Zone)
+	assert.Equal(t, []Event{{Type: Died, Player: b, Tick: 2, Cause: HitWall, Killer: NoPlayer}}, events)
+	assert.Nil(t, g.Tiles[0][0])
+	assert.Nil(t, g.Tiles[0][2])
+	assert.IsType(t, &OutOfBounds{}, g.Get(&Coordinate{0, 5}))
+
+	// a is still inside, on the edge, and walks out
+	assert.Equal(t, 1, g.ActivePlayers[a].Row)
+	events = g.Step(map[PlayerID]Input{a: {Turns: []int{Up}}})
+	assert.Equal(t, []Event{{Type: Died, Player: a, Tick: 3, Cause: HitWall, Killer: NoPlayer}}, events)
+
+	for i := 0; i < 5; i++ {
+		g.Step(nil)
+	}
+	assert.Equal(t, Zone{Row: 2, Col: 2, Size: 6}, g.Zone)
+
+	row, col := g.FindRandomEmptyLocation()
+	assert.True(t, g.Zone.Contains(row, col))
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The rules that differ from one kind of game to another, Config.Mode picks one by name
+// The engine calls the hooks while it runs AddPlayer and Step, a mode can change the game from any of them
+type GameMode interface {
+	// A snake was just put on the map
+	Spawned(g *Game, id PlayerID)
+
+	// A step is starting, Game.Tick has already counted it and nobody has moved yet
+	Tick(g *Game)
+
+	// A snake was just removed from ActivePlayers, event is the Died event for it
+	Died(g *Game, event Event)
+
+	// Whether the game is finished, checked by whoever drives Step
+	Over(g *Game) bool
+
+	// Every player best first, the order they're paid out in
+	Standings(g *Game) []PlayerID
+}
+
+// Builds a mode's state for one game, failing if config is missing something the mode needs
+type ModeBuilder func(config Config) (GameMode, error)
+
+var modes = map[string]ModeBuilder{}
+
+// Makes a mode available to Config.Mode, called from the init of the file defining it
+func RegisterMode(name string, build ModeBuilder) {
+	if _, taken := modes[name]; taken {
+		panic(fmt.Sprintf("mode %q registered twice", name))
+	}
+	modes[name] = build
+}
+
+// Names Config.Mode accepts
+func ModeNames() []string {
+	names := make([]string, 0, len(modes))
+	for name := range modes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Builds the mode config selects, an empty name is classic
+func NewMode(config Config) (GameMode, error) {
+	name := config.Mode
+	if name == "" {
+		name = "classic"
+	}
+
+	build, found := modes[name]
+	if !found {
+		return nil, fmt.Errorf("unknown mode %q", name)
+	}
+	return build(config)
+}
+
+// Modes that end on time, frames count down to it
+type Clock interface {
+	// Ticks until the end, 0 once it's reached
+	TicksLeft(g *Game) int
+}
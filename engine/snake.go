@@ -0,0 +1,64 @@
+package engine
+
+func (g *Game) AddNewSnakeToWorld(sn *SnakeNode) {
+	sn.Row, sn.Col = g.FindRandomEmptyLocation()
+	sn.Length = 1
+
+	g.Tiles[sn.Row][sn.Col] = sn
+	g.SpawnFoodAtRandomLocation(g.Config.FoodPerPlayer)
+}
+
+// Moves the snake from ActivePlayers to LostPlayers and turns its body into food, the parts outside the zone just go
+func (g *Game) Dead(snake *SnakeNode, cause Cause, killer PlayerID) {
+	lastHead := snake
+	player := lastHead.Player
+
+	g.LostPlayers[player] = lastHead
+	g.Eliminated = append(g.Eliminated, player)
+	g.DiedAt[player] = g.Tick
+	delete(g.ActivePlayers, player)
+
+	tempSN := lastHead
+	for tempSN != nil {
+		if g.Zone.Contains(tempSN.Row, tempSN.Col) {
+			g.SpawnFoodAtLocation(tempSN.Row, tempSN.Col)
+		} else {
+			g.Tiles[tempSN.Row][tempSN.Col] = nil
+		}
+		tempSN = tempSN.Next
+	}
+
+	lastHead.Next = nil
+
+	g.emit(Died, player, cause, killer)
+	g.Mode.Died(g, g.events[len(g.events)-1])
+}
+
+// Directions players steer with
+const (
+	Up = iota
+	Right
+	Down
+	Left
+)
+
+// The direction that points back the way direction came
+func Opposite(direction int) int {
+	return (direction + 2) % 4
+}
+
+// How far a head moves along each axis for one step in direction
+func DirectionToRowCol(direction int) (int, int) {
+	switch direction {
+	case Up:
+		return -1, 0
+	case Right:
+		return 0, 1
+	case Down:
+		return 1, 0
+	case Left:
+		return 0, -1
+	default:
+		return 0, 0
+	}
+}
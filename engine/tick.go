@@ -0,0 +1,204 @@
+package engine
+
+// One snake's step, worked out before any snake moves
+type move struct {
+	snake    *SnakeNode
+	tail     *SnakeNode
+	row, col int
+	eats     bool
+
+	dead   bool
+	cause  Cause
+	killer PlayerID
+
+	// Head of the snake this one beat swapping places, its tile is this one's to take
+	beat *SnakeNode
+}
+
+// Moves the live snakes among movers one tile each, all at once
+// Where every head is going is decided first, then conflicts are settled, then the survivors move:
+//   - A tail moving on frees its tile for this move, unless its snake eats or dies
+//   - Heads swapping places or going for the same tile are head on, the longest snake survives and on a tie none do
+//
+// The result doesn't depend on the order of movers
+func (g *Game) Advance(movers []PlayerID) {
+	var moves []*move
+	for _, id := range movers {
+		snake, alive := g.ActivePlayers[id]
+		if !alive {
+			continue
+		}
+
+		dRow, dCol := DirectionToRowCol(g.NextDirection(id))
+		m := &move{
+			snake: snake,
+			tail:  tailOf(snake),
+			row:   snake.Row + dRow,
+			col:   snake.Col + dCol,
+		}
+		_, m.eats = g.Get(&Coordinate{Row: m.row, Col: m.col}).(*FoodNode)
+		moves = append(moves, m)
+	}
+
+	settleSwaps(moves)
+	for g.settle(moves) {
+	}
+
+	// Tails come off before any head goes down, a head may be moving onto one
+	for _, m := range moves {
+		switch {
+		case m.dead:
+			g.Dead(m.snake, m.cause, m.killer)
+		case !m.eats:
+			g.removeTail(m.snake)
+		}
+	}
+
+	for _, m := range moves {
+		if !m.dead {
+			g.advanceHead(m)
+		}
+	}
+}
+
+// Snakes heading into each other's heads, which they'd otherwise pass through if one is a lone head
+func settleSwaps(moves []*move) {
+	for i, a := range moves {
+		for _, b := range moves[i+1:] {
+			if a.row == b.snake.Row && a.col == b.snake.Col && b.row == a.snake.Row && b.col == a.snake.Col {
+				headOn([]*move{a, b})
+				switch {
+				case !a.dead:
+					a.beat = b.snake
+				case !b.dead:
+					b.beat = a.snake
+				}
+			}
+		}
+	}
+}
+
+// One pass over the moves still going ahead, true if it killed anyone
+// A death keeps that snake's tail in place, which can doom whoever was moving onto it, so this runs until nothing changes
+func (g *Game) settle(moves []*move) bool {
+	vacated := map[Coordinate]bool{}
+	for _, m := range moves {
+		if !m.dead && !m.eats {
+			vacated[Coordinate{Row: m.tail.Row, Col: m.tail.Col}] = true
+		}
+	}
+
+	changed := false
+	contests := map[Coordinate][]*move{}
+	for _, m := range moves {
+		if m.dead {
+			continue
+		}
+
+		target := Coordinate{Row: m.row, Col: m.col}
+		switch hit := g.Get(&target).(type) {
+		case *OutOfBounds:
+			m.kill(HitWall, NoPlayer)
+			changed = true
+			continue
+		case *SnakeNode:
+			if vacated[target] || m.beat != nil {
+				break
+			}
+
+			if hit.Player == m.snake.Player {
+				m.kill(HitSelf, NoPlayer)
+			} else {
+				m.kill(HitBody, hit.Player)
+			}
+			changed = true
+			continue
+		}
+
+		contests[target] = append(contests[target], m)
+	}
+
+	for _, contenders := range contests {
+		if len(contenders) > 1 {
+			headOn(contenders)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func (m *move) kill(cause Cause, killer PlayerID) {
+	m.dead, m.cause, m.killer = true, cause, killer
+}
+
+// Kills all but the longest of the snakes, or all of them if more than one is longest
+func headOn(contenders []*move) {
+	var winner *move
+	longest, count := 0, 0
+	for _, m := range contenders {
+		switch {
+		case m.snake.Length > longest:
+			winner, longest, count = m, m.snake.Length, 1
+		case m.snake.Length == longest:
+			count++
+		}
+	}
+
+	killer := NoPlayer
+	if count == 1 {
+		killer = winner.snake.Player
+	}
+
+	for _, m := range contenders {
+		if m.snake.Length < longest || count > 1 {
+			m.kill(HeadOn, killer)
+		}
+	}
+}
+
+func tailOf(snake *SnakeNode) *SnakeNode {
+	for snake.Next != nil {
+		snake = snake.Next
+	}
+	return snake
+}
+
+func (g *Game) removeTail(snake *SnakeNode) {
+	tail := tailOf(snake)
+	g.Tiles[tail.Row][tail.Col] = nil
+
+	for node := snake; node.Next != nil; node = node.Next {
+		if node.Next == tail {
+			node.Next = nil
+			return
+		}
+	}
+}
+
+// Puts the snake's new head down, growing it if it ate
+// The tail is already gone for snakes that didn't eat, a lone head leaves nothing behind
+func (g *Game) advanceHead(m *move) {
+	newHead := &SnakeNode{
+		Row:    m.row,
+		Col:    m.col,
+		Player: m.snake.Player,
+		Length: m.snake.Length,
+		Next:   m.snake,
+	}
+
+	if m.tail == m.snake && !m.eats {
+		newHead.Next = nil
+	}
+
+	if m.eats {
+		newHead.Length++
+		for node := m.snake; node != nil; node = node.Next {
+			node.Length = newHead.Length
+		}
+		g.emit(Ate, m.snake.Player, NoCause, NoPlayer)
+	}
+
+	g.ActivePlayers[newHead.Player] = newHead
+	g.Tiles[m.row][m.col] = newHead
+}
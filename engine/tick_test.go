@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Replaces a spawned snake with one lying on the given tiles, head first, heading the way the head points
+func lay(g *Game, id PlayerID, heading int, tiles ...Coordinate) {
+	old := g.ActivePlayers[id]
+	g.Tiles[old.Row][old.Col] = nil
+
+	var next *SnakeNode
+	for i := len(tiles) - 1; i >= 0; i-- {
+		node := &SnakeNode{Player: id, Length: len(tiles), Row: tiles[i].Row, Col: tiles[i].Col, Next: next}
+		g.Tiles[node.Row][node.Col] = node
+		next = node
+	}
+
+	g.ActivePlayers[id] = next
+	g.Headings[id] = heading
+}
+
+func newTickGame(players int) (*Game, []PlayerID) {
+	g := New(Config{ScalingFactor: 10, SprintFactor: 2}, 1)
+	ids := make([]PlayerID, players)
+	for i := range ids {
+		ids[i] = g.AddPlayer()
+	}
+	return g, ids
+}
+
+type death struct {
+	cause  Cause
+	killer PlayerID
+}
+
+func deaths(events []Event) map[PlayerID]death {
+	died := map[PlayerID]death{}
+	for _, event := range events {
+		if event.Type == Died {
+			died[event.Player] = death{event.Cause, event.Killer}
+		}
+	}
+	return died
+}
+
+func TestStep_HeadOnSwapTie(t *testing.T) {
+	g, ids := newTickGame(2)
+	lay(g, ids[0], Right, Coordinate{Row: 5, Col: 4})
+	lay(g, ids[1], Left, Coordinate{Row: 5, Col: 5})
+
+	// Lone heads would otherwise pass through each other
+	died := deaths(g.Step(map[PlayerID]Input{}))
+	assert.Equal(t, map[PlayerID]death{ids[0]: {HeadOn, NoPlayer}, ids[1]: {HeadOn, NoPlayer}}, died)
+}
+
+func TestStep_HeadOnSwapLongerWins(t *testing.T) {
+	g, ids := newTickGame(2)
+	lay(g, ids[0], Right, Coordinate{Row: 5, Col: 4}, Coordinate{Row: 5, Col: 3})
+	lay(g, ids[1], Left, Coordinate{Row: 5, Col: 5})
+
+	died := deaths(g.Step(map[PlayerID]Input{}))
+	assert.Equal(t, map[PlayerID]death{ids[1]: {HeadOn, ids[0]}}, died)
+	assert.Equal(t, 5, g.ActivePlayers[ids[0]].Col)
+}
+
+func TestStep_SameTile(t *testing.T) {
+	for _, order := range [][2]int{{0, 1}, {1, 0}} {
+		g, ids := newTickGame(2)
+		long, short := ids[order[0]], ids[order[1]]
+		lay(g, long, Down, Coordinate{Row: 4, Col: 5}, Coordinate{Row: 3, Col: 5})
+		lay(g, short, Up, Coordinate{Row: 6, Col: 5})
+		g.SpawnFoodAtLocation(5, 5)
+
+		// Join order makes no difference, the longer snake gets the food
+		events := g.Step(map[PlayerID]Input{})
+		assert.Equal(t, map[PlayerID]death{short: {HeadOn, long}}, deaths(events))
+		assert.Contains(t, events, Event{Type: Ate, Player: long, Tick: 1, Killer: NoPlayer})
+		assert.Equal(t, 3, g.ActivePlayers[long].Length)
+	}
+}
+
+func TestStep_SameTileTie(t *testing.T) {
+	g, ids := newTickGame(3)
+	lay(g, ids[0], Down, Coordinate{Row: 4, Col: 5})
+	lay(g, ids[1], Up, Coordinate{Row: 6, Col: 5})
+	lay(g, ids[2], Left, Coordinate{Row: 5, Col: 6})
+
+	died := deaths(g.Step(map[PlayerID]Input{}))
+	assert.Equal(t, map[PlayerID]death{ids[0]: {HeadOn, NoPlayer}, ids[1]: {HeadOn, NoPlayer}, ids[2]: {HeadOn, NoPlayer}}, died)
+	assert.IsType(t, &FoodNode{}, g.Tiles[6][5])
+}
+
+func TestStep_TailsVacateFirst(t *testing.T) {
+	g, ids := newTickGame(3)
+
+	// a chases its own tail round a square, b follows right behind c
+	lay(g, ids[0], Up, Coordinate{Row: 4, Col: 4}, Coordinate{Row: 5, Col: 4}, Coordinate{Row: 5, Col: 5}, Coordinate{Row: 4, Col: 5})
+	lay(g, ids[1], Right, Coordinate{Row: 8, Col: 4})
+	lay(g, ids[2], Right, Coordinate{Row: 8, Col: 5})
+	g.Queue(ids[0], []int{Right})
+
+	events := g.Step(map[PlayerID]Input{})
+	assert.Empty(t, deaths(events))
+	assert.Equal(t, g.ActivePlayers[ids[0]], g.Tiles[4][5])
+	assert.Nil(t, g.Tiles[4][4].(*SnakeNode).Next.Next.Next)
+	assert.Equal(t, g.ActivePlayers[ids[1]], g.Tiles[8][5])
+	assert.Equal(t, g.ActivePlayers[ids[2]], g.Tiles[8][6])
+	assert.Nil(t, g.Tiles[8][4])
+}
+
+func TestStep_TailStaysWhenEating(t *testing.T) {
+	g, ids := newTickGame(2)
+	lay(g, ids[0], Up, Coordinate{Row: 4, Col: 4}, Coordinate{Row: 5, Col: 4})
+	lay(g, ids[1], Up, Coordinate{Row: 6, Col: 4})
+	g.SpawnFoodAtLocation(3, 4)
+
+	died := deaths(g.Step(map[PlayerID]Input{}))
+	assert.Equal(t, map[PlayerID]death{ids[1]: {HitBody, ids[0]}}, died)
+	assert.Equal(t, 3, g.ActivePlayers[ids[0]].Length)
+}
+
+func TestStep_TailStaysWhenDying(t *testing.T) {
+	g, ids := newTickGame(2)
+
+	// a runs into the wall so its tail never moves, and b was counting on it moving
+	lay(g, ids[0], Up, Coordinate{Row: 0, Col: 4}, Coordinate{Row: 1, Col: 4})
+	lay(g, ids[1], Up, Coordinate{Row: 2, Col: 4})
+
+	died := deaths(g.Step(map[PlayerID]Input{}))
+	assert.Equal(t, map[PlayerID]death{ids[0]: {HitWall, NoPlayer}, ids[1]: {HitBody, ids[0]}}, died)
+}
+
+func TestStep_SprintRounds(t *testing.T) {
+	g, ids := newTickGame(2)
+
+	// The walker takes the tile in the first round, so the sprinter's second step hits it instead of passing
+	lay(g, ids[0], Right, Coordinate{Row: 5, Col: 2})
+	lay(g, ids[1], Down, Coordinate{Row: 4, Col: 4})
+
+	died := deaths(g.Step(map[PlayerID]Input{ids[0]: {Sprinting: true}}))
+	assert.Equal(t, map[PlayerID]death{ids[0]: {HitBody, ids[1]}}, died)
+	assert.Equal(t, Coordinate{Row: 5, Col: 4}, Coordinate{Row: g.ActivePlayers[ids[1]].Row, Col: g.ActivePlayers[ids[1]].Col})
+}
+
+func TestStep_HitSelf(t *testing.T) {
+	g, ids := newTickGame(1)
+	lay(g, ids[0], Up, Coordinate{Row: 4, Col: 4}, Coordinate{Row: 5, Col: 4}, Coordinate{Row: 5, Col: 5}, Coordinate{Row: 4, Col: 5}, Coordinate{Row: 3, Col: 5})
+
+	died := deaths(g.Step(map[PlayerID]Input{ids[0]: {Turns: []int{Right}}}))
+	assert.Equal(t, map[PlayerID]death{ids[0]: {HitSelf, NoPlayer}}, died)
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"errors"
+	"sort"
+)
+
+func init() {
+	RegisterMode("timed", func(config Config) (GameMode, error) {
+		if config.DurationTicks < 1 {
+			return nil, errors.New("timed mode needs a DurationTicks of at least 1")
+		}
+
+		return &Timed{
+			Duration: config.DurationTicks,
+			lengths:  map[PlayerID]int{},
+			reached:  map[PlayerID]int{},
+		}, nil
+	})
+}
+
+// Runs for Duration ticks, then the longest snake alive wins
+// Snakes the same length place by which got there first, the dead place behind the living as in Classic
+type Timed struct {
+	Duration int
+
+	// Length of every snake as of the last look, and the tick it grew to it
+	lengths map[PlayerID]int
+	reached map[PlayerID]int
+}
+
+func (m *Timed) Spawned(g *Game, id PlayerID) {
+	m.lengths[id] = g.ActivePlayers[id].Length
+	m.reached[id] = g.Tick
+}
+
+// Nobody has moved yet this tick, so any growth happened on the last one
+func (m *Timed) Tick(g *Game) {
+	m.catchUp(g, g.Tick-1)
+}
+
+func (m *Timed) Died(g *Game, event Event) {}
+
+func (m *Timed) Over(g *Game) bool {
+	return g.Tick >= m.Duration || len(g.ActivePlayers) <= 1
+}
+
+func (m *Timed) Standings(g *Game) []PlayerID {
+	m.catchUp(g, g.Tick)
+
+	standings := g.SurvivalStandings()
+	live := standings[:len(g.ActivePlayers)]
+	sort.SliceStable(live, func(i, j int) bool {
+		if m.lengths[live[i]] != m.lengths[live[j]] {
+			return m.lengths[live[i]] > m.lengths[live[j]]
+		}
+		return m.reached[live[i]] < m.reached[live[j]]
+	})
+
+	return standings
+}
+
+func (m *Timed) TicksLeft(g *Game) int {
+	if g.Tick >= m.Duration {
+		return 0
+	}
+	return m.Duration - g.Tick
+}
+
+// Notes the snakes that have grown since the last look as having done it on tick
+func (m *Timed) catchUp(g *Game, tick int) {
+	for id, snake := range g.ActivePlayers {
+		if snake.Length != m.lengths[id] {
+			m.lengths[id] = snake.Length
+			m.reached[id] = tick
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package engine
+
+// Identifies a player inside a game, assigned in the order players join
+type PlayerID int
+
+// Tunables the rules depend on, a subset of the server's config
+type Config struct {
+	// Name of the GameMode, see ModeNames, empty is classic
+	Mode string
+
+	// How many ticks a timed match lasts
+	DurationTicks int
+
+	ScalingFactor int
+	FoodPerPlayer int
+	SprintFactor  int
+
+	// Ticks between the safe zone closing in by a tile on every side, 0 leaves it the whole map
+	ZoneInterval int
+
+	// Side the zone stops closing in at
+	ZoneMinSize int
+
+	// Most turns a snake can have waiting, further ones are dropped, 0 doesn't limit them
+	QueueDepth int
+
+	// Every random choice the rules make is drawn from this, so equal seeds replay equally
+	Seed int64
+}
+
+// What a player asked their snake to do this tick
+type Input struct {
+	// Directions pressed since the last tick in the order they were pressed
+	// They're queued and each move takes at most one, so quick turns aren't lost
+	Turns     []int
+	Sprinting bool
+
+	// The player has left, their snake dies before anyone moves
+	Forfeit bool
+}
+
+type OutOfBounds struct{}
+
+type Object interface{}
+
+type SnakeNode struct {
+	Player PlayerID
+	Length int
+	Next   *SnakeNode
+	Row    int
+	Col    int
+}
+
+type FoodNode struct {
+	Row int
+	Col int
+}
+
+type Coordinate struct {
+	Row int
+	Col int
+}
+
+func (g *Game) Get(c *Coordinate) Object {
+	row := c.Row
+	col := c.Col
+
+	if !g.Zone.Contains(row, col) {
+		return &OutOfBounds{}
+	} else {
+		return g.Tiles[row][col]
+	}
+}
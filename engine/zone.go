@@ -0,0 +1,36 @@
+package engine
+
+// The square of the map snakes are safe in, everything outside it is OutOfBounds
+type Zone struct {
+	Row, Col int // Top left tile
+	Size     int
+}
+
+func (z Zone) Contains(row, col int) bool {
+	return row >= z.Row && row < z.Row+z.Size && col >= z.Col && col < z.Col+z.Size
+}
+
+// Takes a tile off every side of the zone every ZoneInterval ticks, until another step would take it under ZoneMinSize
+// Food left outside is cleared as it couldn't be reached, snakes with their head left outside hit the wall there and then
+func (g *Game) shrinkZone() {
+	if g.Config.ZoneInterval < 1 || g.Tick%g.Config.ZoneInterval != 0 || g.Zone.Size-2 < g.Config.ZoneMinSize {
+		return
+	}
+
+	old := g.Zone
+	g.Zone = Zone{Row: old.Row + 1, Col: old.Col + 1, Size: old.Size - 2}
+
+	for row := old.Row; row < old.Row+old.Size; row++ {
+		for col := old.Col; col < old.Col+old.Size; col++ {
+			if _, food := g.Tiles[row][col].(*FoodNode); food && !g.Zone.Contains(row, col) {
+				g.Tiles[row][col] = nil
+			}
+		}
+	}
+
+	for _, id := range g.Players {
+		if snake, alive := g.ActivePlayers[id]; alive && !g.Zone.Contains(snake.Row, snake.Col) {
+			g.Dead(snake, HitWall, NoPlayer)
+		}
+	}
+}
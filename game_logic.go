@@ -1,73 +1,70 @@
 package main
 
 import (
-	"math"
-	"math/rand"
-	"sort"
+	"github.com/moneygames-io/gameserver/engine"
+	"github.com/moneygames-io/gameserver/replay"
 	"time"
 )
 
 // Creates the map based on how many players are destined to join and scaling factor
 func (s *State) CreateMap() {
-	s.World = &Map{}
+	s.Game = engine.New(s.InitialConfig.EngineConfig(), s.SignupCount)
+	s.Players = map[engine.PlayerID]*Player{}
+	s.Replay = replay.New(s.GameID, s.Game.Config, s.SignupCount)
 
-	// Initialize Player Categories
-	s.World.ActivePlayers = map[*Player]*SnakeNode{}
-	s.World.LostPlayers = map[*Player]*SnakeNode{}
-
-	// Compute Map Bounds
-	mapSize := int(math.Sqrt(float64(s.SignupCount))) * s.InitialConfig.ScalingFactor
-
-	// Initialize Map
-	s.World.Tiles = make([][]MapObject, mapSize)
-	for i := range s.World.Tiles {
-		s.World.Tiles[i] = make([]MapObject, mapSize)
-	}
-
-	s.Log.Info("Created an %v x %v map", mapSize, mapSize)
+	s.Log.Info("Created an %v x %v map", len(s.Game.Tiles), len(s.Game.Tiles))
 }
 
+// Closes the lobby and starts the game loop on its own goroutine, call with the lock held
 func (s *State) StartGame() {
-
-	s.Lock()
-	s.Log.Info("Game started")
+	s.Log.Info("Game started with %v players", s.PlayerCount)
+	s.LobbyClosed = true
 	s.Running = true
 	s.FrameRate = s.InitialConfig.FrameRate
-	s.Unlock()
+	s.LoopDone = make(chan struct{})
 
-	s.FrameUpdater()
+	go s.FrameUpdater()
 }
 
-func (s *State) FindRandomEmptyLocation() (int, int) {
-	row := rand.Intn(len(s.World.Tiles))
-	col := rand.Intn(len(s.World.Tiles[0]))
+// Stops a started game loop without paying anyone and waits for it to return, call without the lock held
+func (s *State) StopGame() {
+	s.Lock()
+	s.Running = false
+	done := s.LoopDone
+	s.Unlock()
 
-	if s.World.Get(&Coordinate{row, col}) != nil {
-		return s.FindRandomEmptyLocation()
+	if done != nil {
+		<-done
 	}
-
-	return row, col
-}
-
-func (s *State) IsLocationInBounds(row int, col int) bool {
-	return row > 0 && col > 0 && row < len(s.World.Tiles) && col < len(s.World.Tiles[0])
 }
 
 func (s *State) FrameUpdater() {
-	for s.Running && len(s.World.ActivePlayers) > 1 {
-		s.Log.Info("Current Framerate: %v", s.FrameRate)
+	defer close(s.LoopDone)
 
+	for {
 		startTime := time.Now()
 
 		s.Lock()
+		if !s.Running {
+			s.Unlock()
+			return
+		}
+		if s.GameOver() {
+			s.EndGame()
+			s.Unlock()
+			return
+		}
+
+		s.Log.Info("Current Framerate: %v", s.FrameRate)
 		s.MoveSnakesForward()
 		s.CalculateRankings()
 		s.GenerateMessageModels()
 		s.SerializeMessages()
 		s.SendMessagesToPlayers()
 		s.SendMessagesToSpectators()
-		s.Unlock()
 		rate := time.Second / time.Duration(s.FrameRate)
+		s.Unlock()
+
 		delta := time.Since(startTime)
 
 		sleepAmount := rate.Nanoseconds() - delta.Nanoseconds()
@@ -78,33 +75,69 @@ func (s *State) FrameUpdater() {
 			time.Sleep(time.Duration(sleepAmount))
 		}
 	}
+}
 
-	s.SendWin(s.Rankings[0])
+// Whether the mode has ended the game or at most one human is left to win it
+func (s *State) GameOver() bool {
+	return s.Game.Over() || s.HumansAlive() <= 1
 }
 
+// Pays out the last human standing and writes the replay, call with the lock held
+// When the last humans die on the same tick nobody won, the game is called off so everyone is refunded
+func (s *State) EndGame() {
+	if winner := s.Winner(); winner != nil {
+		s.SendWin(winner)
+	} else {
+		s.Log.Warning("No human survived, aborting game so every signup is refunded")
+		s.AbortGame()
+	}
+	s.SaveReplay()
+}
+
+// The live players in the order the mode would place them if the game ended now
 func (s *State) CalculateRankings() {
-	players := make([]*Player, len(s.World.ActivePlayers))
+	players := make([]*Player, 0, len(s.Game.ActivePlayers))
 
-	index := 0
-	for k := range s.World.ActivePlayers {
-		players[index] = k
-		index++
+	for _, id := range s.Game.Standings() {
+		if _, alive := s.Game.ActivePlayers[id]; alive {
+			players = append(players, s.Players[id])
+		}
 	}
 
-	sort.Slice(players, func(i, j int) bool {
-		return players[i].Snake.Length > players[j].Snake.Length
-	})
-
 	s.Rankings = players
 }
 
+// Hands every player's input to the engine and reacts to what happened
 func (s *State) MoveSnakesForward() {
-	s.Log.Info("Moving %v snakes forward", len(s.World.ActivePlayers))
-	for player, snake := range s.World.ActivePlayers { // TODO what if ActivePlayers changes?
-		if player.Input.Sprinting {
-			s.Sprint(snake)
-		} else {
-			s.Move(snake)
+	s.Log.Info("Moving %v snakes forward", len(s.Game.ActivePlayers))
+
+	s.SteerBots()
+	s.ApplyDisconnectPolicy()
+
+	inputs := map[engine.PlayerID]engine.Input{}
+	for id, player := range s.Players {
+		turns := player.Input.Turns
+		if len(turns) == 0 { // Still wanting the same direction, in case the engine dropped it off a full queue
+			turns = []int{player.Input.Direction}
+		}
+
+		inputs[id] = engine.Input{
+			Turns:     turns,
+			Sprinting: player.Input.Sprinting,
+			Forfeit:   player.Forfeit,
+		}
+		player.Input.Turns = nil
+	}
+
+	s.Replay.RecordTick(inputs)
+	for _, event := range s.Game.Step(inputs) {
+		switch event.Type {
+		case engine.Died:
+			if event.Killer != engine.NoPlayer {
+				s.Players[event.Killer].Kills++
+			}
+			s.SendLoss(s.Players[event.Player])
+			s.SendKillFeed(event)
 		}
 	}
 }
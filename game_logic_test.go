@@ -1,40 +1,53 @@
 package main
 
 import (
+	"github.com/moneygames-io/gameserver/protocol"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
-func TestCreateMap(t *testing.T) {
-	s := &State{
-		SignupCount: 100,
-		InitialConfig: &Config{
-			ScalingFactor: 100,
-		},
-	}
-
-	s.CreateMap()
-	assert.Equal(t, len(s.World.Tiles), 1000)
-	for _, row := range s.World.Tiles {
-		assert.Equal(t, len(row), 1000)
-	}
+func TestEndGame_LastHumanStanding(t *testing.T) {
+	s, done := newTestState(t, 4)
+	defer done()
+	s.InitialConfig.BotDifficulty = "easy"
+
+	transports := lobby(t, s, []string{"a", "b", "c", "d"}, 2)
+	s.FillWithBots()
+	assert.False(t, s.GameOver())
+
+	// The bots are still alive but nobody is left for b to lose to
+	s.Players[0].Forfeit = true
+	s.MoveSnakesForward()
+	assert.Len(t, s.Game.ActivePlayers, 3)
+	assert.True(t, s.GameOver())
+
+	s.EndGame()
+	assert.Equal(t, []protocol.MessageType{protocol.LostMessage, protocol.KillMessage}, transports[0].reliableTypes())
+	assert.Equal(t, []protocol.MessageType{protocol.KillMessage, protocol.WonMessage}, transports[1].reliableTypes())
+	assert.Equal(t, "lost", status(s, "a"))
+	assert.Equal(t, "won", status(s, "b"))
+	assert.Equal(t, s.Players[1].ID, s.Replay.Result.Winner)
 }
 
-func TestState_FindRandomEmptyLocation(t *testing.T) {
-	s := &State{
-		SignupCount: 4,
-		InitialConfig: &Config{
-			ScalingFactor: 4,
-		},
-	}
+func TestEndGame_NoSurvivors(t *testing.T) {
+	s, done := newTestState(t, 3)
+	defer done()
 
-	s.SetupLogger()
-	s.SetRandomSeed()
-	s.CreateMap()
+	transports := lobby(t, s, []string{"a", "b", "c"}, 2)
+	s.Players[0].Forfeit = true
+	s.Players[1].Forfeit = true
+	s.MoveSnakesForward()
+	assert.True(t, s.GameOver())
 
-	assert.Equal(t, 4, len(s.World.Tiles))
-	for _, row := range s.World.Tiles {
-		assert.Equal(t, 4, len(row))
-	}
+	// Neither is paid after being told they lost, the game is called off instead
+	s.EndGame()
+	lost, kill, aborted := protocol.LostMessage, protocol.KillMessage, protocol.AbortedMessage
+	assert.Equal(t, []protocol.MessageType{lost, kill, kill, aborted}, transports[0].reliableTypes())
+	assert.Equal(t, []protocol.MessageType{kill, lost, kill, aborted}, transports[1].reliableTypes())
+	assert.Equal(t, "aborted", status(s, "a"))
+	assert.Equal(t, "aborted", status(s, "b"))
+	assert.Nil(t, s.Replay.Result)
 
+	game, _ := s.GameserverRedis.HGet(s.GameID, "status").Result()
+	assert.Equal(t, "aborted", game)
 }
@@ -2,8 +2,6 @@ package main
 
 import (
 	"github.com/op/go-logging"
-	"github.com/pions/webrtc"
-	"math/rand"
 	"net/http"
 	"os"
 	"strconv"
@@ -15,11 +13,13 @@ func main() {
 
 	state.SetupLogger()
 	state.SetupInitialConfig()
-	state.SetRandomSeed()
 	state.SetupMiscServerVariables()
 	state.BroadcastState()
 	state.SetSignupCount()
+	state.ApplyGameProfile()
+	state.SetRandomSeed()
 	state.CreateMap()
+	state.ScheduleJoinDeadline()
 
 	state.SetupConnectionHandler()
 }
@@ -36,24 +36,6 @@ func (s *State) SetupLogger() {
 	logging.SetBackend(formatter)
 }
 
-func (s *State) SetupInitialConfig() {
-	s.InitialConfig = &Config{
-		ScalingFactor:   250,
-		FoodPerPlayer:   100,
-		SprintFactor:    2,
-		LeaderboardSize: 2,
-		FrameRate:       7,
-		DefaultZoom:     10,
-		RTCSettings: webrtc.RTCConfiguration{
-			IceServers: []webrtc.RTCIceServer{
-				{
-					URLs: []string{"stun:stun.l.google.com:19302"},
-				},
-			},
-		},
-	}
-}
-
 func (s *State) SetupMiscServerVariables() {
 	// Redis stuff
 	s.GameserverRedis = connectToRedis("redis-gameservers:6379", s.Log)
@@ -82,10 +64,14 @@ func (s *State) BroadcastState() {
 	s.GameserverRedis.HSet(s.GameID, "status", "idle")
 }
 
+// Picks the seed for this game's RNG and records it so the game can be regenerated
 func (s *State) SetRandomSeed() {
-	now := time.Now().UnixNano()
-	rand.Seed(now)
-	s.Log.Debug("Seed: %v", now)
+	if s.InitialConfig.Seed == 0 {
+		s.InitialConfig.Seed = time.Now().UnixNano()
+	}
+
+	s.Log.Info("Seed: %v", s.InitialConfig.Seed)
+	s.GameserverRedis.HSet(s.GameID, "seed", s.InitialConfig.Seed)
 }
 
 func (s *State) SetSignupCount() {
@@ -121,5 +107,7 @@ func corsHandler(h http.HandlerFunc) http.HandlerFunc {
 func (s *State) SetupConnectionHandler() {
 
 	http.Handle("/player", corsHandler(s.NewPlayer))
+	http.Handle("/player/ws", corsHandler(s.NewWebSocketPlayer))
+	http.Handle("/replay", corsHandler(s.ServeReplay))
 	s.Log.Fatal(http.ListenAndServe(":10000", nil))
 }
@@ -1,22 +1,71 @@
 package main
 
 import (
-	"github.com/pions/webrtc/pkg/datachannel"
+	"fmt"
+	"github.com/moneygames-io/gameserver/protocol"
 )
 
-// TODO Validate Sprint
-// TODO Validate Zoom level
-func (s *State) HandlePlayerInput(payload datachannel.Payload, player *Player) {
+// Applies a client's protocol.Input that arrived on t, anything malformed is dropped and counted against the player
+// Inputs that arrive after a later one was applied are stale rather than malformed, they're dropped quietly
+// So are inputs on a transport the player isn't using, one they've replaced or one they're yet to be given
+func (s *State) HandlePlayerInput(data []byte, player *Player, t Transport) {
 	s.Lock()
 	defer s.Unlock()
 
-	value := payload.(*datachannel.PayloadBinary).Data // TODO Can crash server
+	if player.Connection != t {
+		return
+	}
 
-	player.Input.Direction = int(value[0])
-	player.Input.ZoomLevel = int(value[1])
-	player.Input.Sprinting = value[2] == 1
+	input, err := protocol.DecodeInput(data)
+	if err == nil {
+		err = s.ValidateInput(input)
+	}
+	if err != nil {
+		s.RejectInput(player, err)
+		return
+	}
+
+	if input.Sequence <= player.InputSequence {
+		return
+	}
+
+	player.InputSequence = input.Sequence
+	depth := s.InitialConfig.QueueDepth
+	if input.Direction != player.Input.Direction && (depth == 0 || len(player.Input.Turns) < depth) {
+		player.Input.Turns = append(player.Input.Turns, input.Direction)
+	}
+	player.Input.Direction = input.Direction
+	player.Input.ZoomLevel = input.Zoom
+	player.Input.Sprinting = input.Sprinting
+
+	if input.AckedTick > player.AckedTick {
+		player.AckedTick = input.AckedTick
+	}
+}
+
+// Checks the parts of an input that depend on the config and the game
+func (s *State) ValidateInput(input protocol.Input) error {
+	if input.Zoom < s.InitialConfig.MinZoom || input.Zoom > s.InitialConfig.MaxZoom {
+		return fmt.Errorf("zoom %v outside %v-%v", input.Zoom, s.InitialConfig.MinZoom, s.InitialConfig.MaxZoom)
+	}
+
+	if input.AckedTick > s.Game.Tick {
+		return fmt.Errorf("acked tick %v hasn't happened yet", input.AckedTick)
+	}
+
+	return nil
+}
+
+// Only the first rejection is logged as a warning so a broken client can't flood the log
+func (s *State) RejectInput(player *Player, err error) {
+	player.RejectedInputs++
+	if player.RejectedInputs == 1 {
+		s.Log.Warning("Rejected input from %v: %v", player.Name, err)
+		return
+	}
+	s.Log.Debug("Rejected input %v from %v: %v", player.RejectedInputs, player.Name, err)
 }
 
-func (s *State) HandleSpectatorInput(payload datachannel.Payload, spectator *Spectator) {
+func (s *State) HandleSpectatorInput(data []byte, spectator *Spectator) {
 	// TODO
 }
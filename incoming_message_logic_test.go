@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/moneygames-io/gameserver/protocol"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHandlePlayerInput_QueueDepth(t *testing.T) {
+	for depth, queued := range map[int]int{2: 2, 0: 5} {
+		s, done := newTestState(t, 2)
+		s.InitialConfig.QueueDepth = depth
+
+		transport := lobby(t, s, []string{"a", "b"}, 1)[0]
+		player := s.Players[0]
+		for i := 1; i <= 5; i++ {
+			s.HandlePlayerInput(input(uint32(i), i%4), player, transport)
+		}
+
+		assert.Len(t, player.Input.Turns, queued, "queue_depth %v", depth)
+		assert.Equal(t, 1, player.Input.Direction)
+		done()
+	}
+}
+
+func TestHandlePlayerInput_Acks(t *testing.T) {
+	s, done := newTestState(t, 3)
+	defer done()
+
+	transports := lobby(t, s, []string{"a", "b", "c"}, 2)
+	silent, acking := s.Players[0], s.Players[1]
+
+	for tick := 1; tick <= 5; tick++ {
+		s.MoveSnakesForward()
+		s.CalculateRankings()
+		s.GenerateMessageModels()
+		s.SerializeMessages()
+		s.SendMessagesToPlayers()
+
+		// Going straight the client still answers every frame, repeating its direction
+		s.HandlePlayerInput(protocol.EncodeInput(protocol.Input{
+			Direction: acking.Input.Direction,
+			Zoom:      acking.Input.ZoomLevel,
+			Sequence:  uint32(tick),
+			AckedTick: tick,
+		}), acking, transports[1])
+		assert.Empty(t, acking.Input.Turns)
+	}
+
+	// A client that sends nothing never acknowledges a frame, so it only gets keyframes
+	assert.Equal(t, []protocol.MessageType{
+		protocol.FrameMessage, protocol.FrameMessage, protocol.FrameMessage, protocol.FrameMessage, protocol.FrameMessage,
+	}, transports[0].unreliableTypes())
+	assert.Equal(t, 0, silent.AckedTick)
+
+	assert.Equal(t, []protocol.MessageType{
+		protocol.FrameMessage, protocol.DeltaMessage, protocol.DeltaMessage, protocol.DeltaMessage, protocol.DeltaMessage,
+	}, transports[1].unreliableTypes())
+	assert.Equal(t, 5, acking.AckedTick)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// Gives players JoinTimeout seconds from now to connect, see JoinDeadlinePassed
+func (s *State) ScheduleJoinDeadline() {
+	if s.InitialConfig.JoinTimeout == 0 {
+		return
+	}
+
+	s.Log.Info("Players have %v seconds to join", s.InitialConfig.JoinTimeout)
+	go func() {
+		time.Sleep(time.Duration(s.InitialConfig.JoinTimeout) * time.Second)
+		s.JoinDeadlinePassed()
+	}()
+}
+
+// Starts with whoever made it, topping up with bots if configured, or aborts below MinPlayers
+func (s *State) JoinDeadlinePassed() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.LobbyClosed {
+		return
+	}
+
+	s.Log.Info("Join deadline passed with %v of %v players", s.PlayerCount, s.SignupCount)
+	s.LobbyClosed = true
+
+	err := s.MarkNoShows()
+	if err != nil {
+		s.Log.Error("Aborting game so every signup is refunded, no shows can't be marked: %v", err)
+		s.AbortGame()
+		return
+	}
+
+	if s.PlayerCount < s.InitialConfig.MinPlayers {
+		s.Log.Warning("Aborting game, %v players joined but %v are needed", s.PlayerCount, s.InitialConfig.MinPlayers)
+		s.AbortGame()
+		return
+	}
+
+	if s.InitialConfig.BotDifficulty != "" {
+		s.FillWithBots()
+	}
+
+	s.StartGame()
+}
+
+// Marks every token the matchmaker signed up for this game that never joined, see GameserverRedis
+// Fails without marking anyone if the signups can't be read, a game always has some
+func (s *State) MarkNoShows() error {
+	signups, err := s.GameserverRedis.SMembers(s.GameID + ":signups").Result()
+	if err != nil {
+		return err
+	}
+	if len(signups) == 0 {
+		return errors.New("no signups listed")
+	}
+
+	joined := map[string]bool{}
+	for _, player := range s.Players {
+		joined[player.Token] = true
+	}
+
+	for _, token := range signups {
+		if joined[token] {
+			continue
+		}
+
+		s.Log.Info("Marking %v as a no show", hash(token))
+		s.PlayerRedis.HSet(token, "status", "no show")
+		s.PlayerRedis.HSet(token, "game", s.GameID)
+	}
+
+	return nil
+}
+
+// Calls the game off, everyone who joined is told and payments refunds every signup
+func (s *State) AbortGame() {
+	s.GameserverRedis.HSet(s.GameID, "status", "aborted")
+
+	for _, player := range s.Players {
+		s.SendAborted(player)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"github.com/moneygames-io/gameserver/protocol"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Signs up every token and has the first joined of them connect
+func lobby(t *testing.T, s *State, tokens []string, joined int) []*recordingTransport {
+	var transports []*recordingTransport
+	for i, token := range tokens {
+		signUp(s, token)
+		if i >= joined {
+			continue
+		}
+
+		player, err := s.PlayerForToken(token, token)
+		assert.Nil(t, err)
+
+		transport := &recordingTransport{}
+		assert.True(t, s.OnBoardPlayer(player, transport))
+		transports = append(transports, transport)
+	}
+	return transports
+}
+
+func status(s *State, token string) string {
+	status, _ := s.PlayerRedis.HGet(token, "status").Result()
+	return status
+}
+
+func TestJoinDeadlinePassed_Start(t *testing.T) {
+	s, done := newTestState(t, 3)
+	defer done()
+
+	lobby(t, s, []string{"a", "b", "c"}, 2)
+	s.JoinDeadlinePassed()
+
+	player, err := s.PlayerForToken("c", "c")
+	assert.Nil(t, player)
+	assert.NotNil(t, err)
+
+	// The game loop is running now
+	s.Lock()
+	defer s.Unlock()
+
+	assert.True(t, s.LobbyClosed)
+	assert.True(t, s.Running)
+	assert.Equal(t, 2, s.PlayerCount)
+	assert.Equal(t, "in game", status(s, "a"))
+	assert.Equal(t, "no show", status(s, "c"))
+
+	game, _ := s.PlayerRedis.HGet("c", "game").Result()
+	assert.Equal(t, s.GameID, game)
+}
+
+func TestJoinDeadlinePassed_Abort(t *testing.T) {
+	s, done := newTestState(t, 3)
+	defer done()
+
+	transports := lobby(t, s, []string{"a", "b", "c"}, 1)
+	s.JoinDeadlinePassed()
+
+	assert.True(t, s.LobbyClosed)
+	assert.False(t, s.Running)
+	assert.Equal(t, "aborted", status(s, "a"))
+	assert.Equal(t, "no show", status(s, "b"))
+	assert.Equal(t, []protocol.MessageType{protocol.AbortedMessage}, transports[0].reliableTypes())
+
+	game, _ := s.GameserverRedis.HGet(s.GameID, "status").Result()
+	assert.Equal(t, "aborted", game)
+
+	// Too late to join now
+	player := &Player{Token: "b", Input: &Input{}}
+	assert.False(t, s.OnBoardPlayer(player, &recordingTransport{}))
+}
+
+func TestJoinDeadlinePassed_NoSignups(t *testing.T) {
+	s, done := newTestState(t, 3)
+	defer done()
+
+	transports := lobby(t, s, []string{"a", "b"}, 2)
+	s.GameserverRedis.Del(s.GameID + ":signups")
+	s.JoinDeadlinePassed()
+
+	// Nobody can be told apart as a no show, so the game is off and payments refunds everyone
+	assert.False(t, s.Running)
+	assert.Equal(t, "aborted", status(s, "a"))
+	assert.Equal(t, []protocol.MessageType{protocol.AbortedMessage}, transports[1].reliableTypes())
+
+	game, _ := s.GameserverRedis.HGet(s.GameID, "status").Result()
+	assert.Equal(t, "aborted", game)
+}
+
+func TestJoinDeadlinePassed_Bots(t *testing.T) {
+	s, done := newTestState(t, 4)
+	defer done()
+	s.InitialConfig.BotDifficulty = "easy"
+
+	lobby(t, s, []string{"a", "b", "c", "d"}, 2)
+	s.JoinDeadlinePassed()
+
+	s.Lock()
+	defer s.Unlock()
+
+	assert.True(t, s.Running)
+	assert.Equal(t, 4, s.PlayerCount)
+	assert.Len(t, s.Game.ActivePlayers, 4)
+
+	bots := 0
+	for _, player := range s.Players {
+		if player.Bot != nil {
+			bots++
+		}
+	}
+	assert.Equal(t, 2, bots)
+	assert.Equal(t, "no show", status(s, "d"))
+}
+
+func TestJoinDeadlinePassed_AfterStart(t *testing.T) {
+	s, done := newTestState(t, 2)
+	defer done()
+
+	lobby(t, s, []string{"a", "b"}, 2)
+	s.JoinDeadlinePassed()
+
+	s.Lock()
+	defer s.Unlock()
+
+	assert.True(t, s.Running)
+	assert.Equal(t, "in game", status(s, "b"))
+}
@@ -1,51 +1,67 @@
 package main
 
 import (
-	"github.com/pions/webrtc/pkg/datachannel"
+	"github.com/moneygames-io/gameserver/engine"
+	"github.com/moneygames-io/gameserver/protocol"
+	"github.com/moneygames-io/gameserver/replay"
 	"hash/fnv"
-	"math"
-	"reflect"
-	"unsafe"
 )
 
 func (s *State) GenerateMessageModels() {
-	for rank, player := range s.Rankings {
+	// Leaderboard & Minimap
+	leaderboardSize := s.InitialConfig.LeaderboardSize
+	if leaderboardSize > len(s.Rankings) {
+		leaderboardSize = len(s.Rankings)
+	}
 
-		// Rank & Spectator Count
-		player.Message = &Message{}
+	leaders := make([]protocol.Leader, leaderboardSize)
+	for i, leader := range s.Rankings[:leaderboardSize] {
+		leaders[i] = s.LeaderModel(leader)
+	}
 
-		// Leaderboard & Minimap
-		leaderboardSize := int(math.Min(float64(s.InitialConfig.LeaderboardSize), float64(len(s.Rankings))))
+	zone := protocol.Zone{
+		Row:  int32(s.Game.Zone.Row),
+		Col:  int32(s.Game.Zone.Col),
+		Size: int32(s.Game.Zone.Size),
+	}
 
-		if rank < leaderboardSize {
-			player.Message.LeaderMap = s.Rankings[0:leaderboardSize]
-		} else {
-			player.Message.LeaderMap = append(s.Rankings[0:leaderboardSize], player)
+	for rank, player := range s.Rankings {
+		if player.Bot != nil {
+			continue
 		}
 
-		// Perspective
-		player.Message.Perspective = map[MapObject]bool{}
-		player.Message.ViewportSize = player.Input.ZoomLevel * 2
-		player.Message.MapSize = len(s.World.Tiles)
+		snake := s.Game.ActivePlayers[player.ID]
+		zoom := player.Input.ZoomLevel
 
-		p0Row := player.Snake.Row - player.Input.ZoomLevel
-		p0Col := player.Snake.Col - player.Input.ZoomLevel
+		frame := &protocol.Frame{
+			TopLeftRow:    int32(snake.Row - zoom),
+			TopLeftCol:    int32(snake.Col - zoom),
+			ViewportSize:  int32(zoom * 2),
+			MapSize:       int32(len(s.Game.Tiles)),
+			InputSequence: player.InputSequence,
+			TicksLeft:     int32(s.Game.TicksLeft()),
+			Zone:          zone,
+			Leaders:       leaders,
+		}
 
-		player.Message.TopLeft = &Coordinate{p0Row, p0Col}
+		if rank >= leaderboardSize {
+			frame.Leaders = append(leaders[:leaderboardSize:leaderboardSize], s.LeaderModel(player))
+		}
 
-		for row := 0; row < player.Input.ZoomLevel*2; row++ {
-			for col := 0; col < player.Input.ZoomLevel*2; col++ {
+		// Perspective
+		frame.Snakes = s.SnakeSegments(snake.Row-zoom, snake.Col-zoom, zoom*2)
 
-				coordinate := &Coordinate{row + p0Row, col + p0Col}
+		for row := snake.Row - zoom; row < snake.Row+zoom; row++ {
+			for col := snake.Col - zoom; col < snake.Col+zoom; col++ {
 
-				switch v := s.World.Get(coordinate).(type) {
-				case *SnakeNode:
-					player.Message.Perspective[v] = true
-					break
-				case *FoodNode:
-					player.Message.Perspective[v] = true
-					break
-				case *OutOfBounds:
+				switch v := s.Game.Get(&engine.Coordinate{Row: row, Col: col}).(type) {
+				case *engine.FoodNode:
+					frame.Food = append(frame.Food, protocol.FoodTile{
+						Row: int32(v.Row),
+						Col: int32(v.Col),
+					})
+				case *engine.SnakeNode: // Already in the segments
+				case *engine.OutOfBounds:
 				case nil:
 					continue
 				default:
@@ -53,68 +69,122 @@ func (s *State) GenerateMessageModels() {
 				}
 			}
 		}
+
+		player.Message = &Message{Frame: frame}
 	}
 }
 
-func (s *State) SerializeMessages() {
-	for _, player := range s.Rankings {
+// Snake nodes inside the size x size square at top, left, following each snake from its head
+// Every unbroken stretch of a snake that's in view becomes its own segment
+func (s *State) SnakeSegments(top, left, size int) []protocol.SnakeSegment {
+	inView := func(node *engine.SnakeNode) bool {
+		return node.Row >= top && node.Row < top+size && node.Col >= left && node.Col < left+size
+	}
 
-		player.Message.Serialized = []rune{
-			FrameMessage,
-			int32(player.Message.TopLeft.Row),
-			int32(player.Message.TopLeft.Col),
-			int32(player.Message.ViewportSize),
-			int32(player.Message.MapSize),
+	var segments []protocol.SnakeSegment
+	for _, id := range s.Game.Players {
+		head, alive := s.Game.ActivePlayers[id]
+		if !alive {
+			continue
 		}
 
-		for mo := range player.Message.Perspective {
-			switch v := mo.(type) {
-			case *SnakeNode:
-				sn := []rune{hash(v.Player.Token), int32(v.Row), int32(v.Col)}
-				player.Message.Serialized = append(player.Message.Serialized, sn...)
-				break
-			case *FoodNode:
-				fn := []rune{'F', int32(v.Row), int32(v.Col)}
-				player.Message.Serialized = append(player.Message.Serialized, fn...)
-				break
+		var segment *protocol.SnakeSegment
+		for node := head; node != nil; node = node.Next {
+			switch {
+			case !inView(node) && segment != nil:
+				segments = append(segments, *segment)
+				segment = nil
+			case !inView(node):
+			case segment == nil:
+				segment = &protocol.SnakeSegment{
+					Player: hash(s.Players[id].Token),
+					Row:    int32(node.Row),
+					Col:    int32(node.Col),
+				}
+			default:
+				segment.Extend(int32(node.Row), int32(node.Col))
 			}
 		}
 
-		player.Message.Serialized = append(player.Message.Serialized, -1)
-
-		for _, member := range player.Message.LeaderMap {
-			player.Message.Serialized = append(player.Message.Serialized, []rune(member.Name)...)
-			player.Message.Serialized = append(player.Message.Serialized, []rune{
-				-2,
-				int32(hash(player.Token)),
-				int32(player.Snake.Row),
-				int32(player.Snake.Col),
-				int32(player.Snake.Length),
-				int32(player.SpectatorCount),
-			}...)
+		if segment != nil {
+			segments = append(segments, *segment)
+		}
+	}
+
+	return segments
+}
+
+// How a player appears on everyone's leaderboard
+func (s *State) LeaderModel(player *Player) protocol.Leader {
+	snake := s.Game.ActivePlayers[player.ID]
+
+	var flags int32
+	if player.Bot != nil {
+		flags |= protocol.FlagBot
+	}
+
+	return protocol.Leader{
+		Player:     hash(player.Token),
+		Row:        int32(snake.Row),
+		Col:        int32(snake.Col),
+		Length:     int32(snake.Length),
+		Spectators: int32(player.SpectatorCount),
+		Flags:      flags,
+		Name:       player.Name,
+	}
+}
+
+func (s *State) SerializeMessages() {
+	for _, player := range s.Rankings {
+		if player.Bot != nil {
+			continue
+		}
+
+		player.Message.Serialized = s.EncodeFrame(player)
+	}
+}
+
+// Sends the frame as a delta against the last one the player acknowledged when possible
+// A keyframe goes out every KeyframeInterval ticks regardless, or when there's no usable baseline
+func (s *State) EncodeFrame(player *Player) []byte {
+	tick := s.Game.Tick
+	interval := s.InitialConfig.KeyframeInterval
+	frame := player.Message.Frame
+
+	if player.SentFrames == nil {
+		player.SentFrames = map[int]*protocol.View{}
+	}
+
+	for sent := range player.SentFrames {
+		if sent < player.AckedTick || sent <= tick-interval {
+			delete(player.SentFrames, sent)
 		}
+	}
+	player.SentFrames[tick] = frame.View()
 
-		player.Message.Serialized = append(player.Message.Serialized, -4)
+	baseline, found := player.SentFrames[player.AckedTick]
+	if !found || tick-player.LastKeyframe >= interval {
+		player.LastKeyframe = tick
+		return protocol.Encode(&player.Buffer, tick, frame)
 	}
+
+	return protocol.Encode(&player.Buffer, tick, protocol.Diff(player.AckedTick, baseline, player.SentFrames[tick]))
 }
 
 func (s *State) SendMessagesToPlayers() {
 	for _, player := range s.Rankings {
-		header := *(*reflect.SliceHeader)(unsafe.Pointer(&player.Message.Serialized))
-		header.Len *= 4
-		header.Cap *= 4
-		data := *(*[]byte)(unsafe.Pointer(&header))
-		_ = player.Connection.Send(datachannel.PayloadBinary{Data: data})
+		if player.Bot != nil {
+			continue
+		}
+
+		s.SendFrameToPlayer(player, player.Message.Serialized)
 	}
 }
 
 func (s *State) SendMessagesToSpectators() {
-	for _, spectator := range s.Spectators {
-		header := *(*reflect.SliceHeader)(unsafe.Pointer(&spectator.CurrentView.Message.Serialized))
-		header.Len *= 4
-		header.Cap *= 4
-		data := *(*[]byte)(unsafe.Pointer(&header))
-		_ = spectator.Connection.Send(datachannel.PayloadBinary{Data: data})
+	for _, spectator := range s.Spectators { // Spectators don't acknowledge, so they always get whole frames
+		data := protocol.Encode(&spectator.Buffer, s.Game.Tick, spectator.CurrentView.Message.Frame)
+		_ = spectator.Connection.SendUnreliable(data)
 	}
 }
 
@@ -122,41 +192,105 @@ func (s *State) SendWin(player *Player) {
 	pot, _ := s.GameserverRedis.HGet(s.GameID, "pot").Result()
 	s.PlayerRedis.HSet(player.Token, "status", "won")
 
-	message := []rune{WonMessage}
-	message = append(message, []rune(pot)...)
-	message = append(message, -1)
+	s.Replay.Result = &replay.Result{
+		Winner:    player.ID,
+		Standings: s.Game.Standings(),
+		Pot:       pot,
+	}
 
-	header := *(*reflect.SliceHeader)(unsafe.Pointer(&message))
-	header.Len *= 4
-	header.Cap *= 4
-	data := *(*[]byte)(unsafe.Pointer(&header))
-	_ = player.Connection.Send(datachannel.PayloadBinary{Data: data})
+	message := protocol.Encode(&protocol.Buffer{}, s.Game.Tick, &protocol.Won{Pot: pot})
+	s.SendToPlayer(player, message)
 }
 
 func (s *State) SendLoss(player *Player) {
-	s.PlayerRedis.HSet(player.Token, "status", "won")
+	if player.Bot != nil {
+		return
+	}
 
-	message := []rune{LostMessage}
+	s.PlayerRedis.HSet(player.Token, "status", "lost")
 
-	header := *(*reflect.SliceHeader)(unsafe.Pointer(&message))
-	header.Len *= 4
-	header.Cap *= 4
-	data := *(*[]byte)(unsafe.Pointer(&header))
-	_ = player.Connection.Send(datachannel.PayloadBinary{Data: data})
+	message := protocol.Encode(&protocol.Buffer{}, s.Game.Tick, &protocol.Lost{})
+	s.SendToPlayer(player, message)
 }
 
-func hash(s string) int32 { // TODO PRE-PRODUCTION Is this secure enough to use for the token?
-	h := fnv.New32a()
-	_, _ = h.Write([]byte(s))
-	hash := h.Sum32()
-	i32 := int32(hash)
-	if i32 < 0 {
-		return -1 * i32
+// Tells every player and spectator who died and who gets the credit
+func (s *State) SendKillFeed(event engine.Event) {
+	victim := s.Players[event.Player]
+	kill := &protocol.Kill{
+		Cause:      killCause(event.Cause),
+		Victim:     hash(victim.Token),
+		VictimName: victim.Name,
 	}
 
-	if i32 == 'F' {
-		return i32 + 1
+	if event.Killer != engine.NoPlayer {
+		killer := s.Players[event.Killer]
+		kill.Killers = []protocol.Killer{{
+			Player: hash(killer.Token),
+			Name:   killer.Name,
+			Kills:  int32(killer.Kills),
+		}}
 	}
 
-	return i32
+	message := protocol.Encode(&protocol.Buffer{}, s.Game.Tick, kill)
+	for _, player := range s.Players {
+		s.SendToPlayer(player, message)
+	}
+	for _, spectator := range s.Spectators {
+		_ = spectator.Connection.SendReliable(message)
+	}
+}
+
+func killCause(cause engine.Cause) protocol.KillCause {
+	switch cause {
+	case engine.HitSelf:
+		return protocol.KillSelf
+	case engine.HitBody:
+		return protocol.KillBody
+	case engine.HeadOn:
+		return protocol.KillHeadOn
+	case engine.Forfeited:
+		return protocol.KillForfeit
+	default:
+		return protocol.KillWall
+	}
+}
+
+func (s *State) SendAborted(player *Player) {
+	if player.Bot != nil {
+		return
+	}
+
+	s.PlayerRedis.HSet(player.Token, "status", "aborted")
+
+	message := protocol.Encode(&protocol.Buffer{}, s.Game.Tick, &protocol.Aborted{})
+	s.SendToPlayer(player, message)
+}
+
+// Sends a message the player can't miss, like how their game ended
+// Drops the message when the player's connection is gone, bots never have one
+func (s *State) SendToPlayer(player *Player, data []byte) {
+	if !player.Connected {
+		return
+	}
+	_ = player.Connection.SendReliable(data)
+}
+
+// Sends a frame, which is fine to lose as the frames after it are built against what the player acknowledged
+func (s *State) SendFrameToPlayer(player *Player, data []byte) {
+	if !player.Connected {
+		return
+	}
+	_ = player.Connection.SendUnreliable(data)
+}
+
+// Transports may hold on to sent bytes after Send returns, so buffers that get reused can't be handed over directly
+func copyBytes(data []byte) []byte {
+	return append([]byte(nil), data...)
+}
+
+// Public identifier for a player, sent in place of their token
+func hash(s string) int32 { // TODO PRE-PRODUCTION Is this secure enough to use for the token?
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return int32(h.Sum32())
 }
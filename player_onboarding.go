@@ -3,11 +3,14 @@ package main
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
 	"github.com/pions/webrtc"
 	"github.com/pions/webrtc/pkg/datachannel"
 	"github.com/pions/webrtc/pkg/ice"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // Entry point for a players
@@ -41,18 +44,13 @@ func (s *State) NewPlayer(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	if !s.tokenIsValid(input["token"]) {
-		http.Error(writer, "Invalid Token", 400)
+	player, err := s.PlayerForToken(input["token"], input["name"])
+	if err != nil {
+		http.Error(writer, err.Error(), 400)
 		return
 	}
 
-	newPlayer := &Player{
-		Token: input["token"],
-		Name:  input["name"],
-		Input: &Input{ZoomLevel: s.InitialConfig.DefaultZoom},
-	}
-
-	answer, err := s.SetupRTCForPlayer(newPlayer, input["offer"])
+	answer, err := s.SetupRTCForPlayer(player, input["offer"])
 	if err != nil {
 		http.Error(writer, "Could not create response", 500)
 	}
@@ -61,25 +59,99 @@ func (s *State) NewPlayer(writer http.ResponseWriter, request *http.Request) {
 	_, _ = writer.Write([]byte(answer))
 }
 
+// How long a new WebSocket has to say who it is
+const webSocketHandshakeTimeout = 10 * time.Second
+
+// Entry point for players on networks that block WebRTC
+// The client's first message is the JSON NewPlayer takes, without the offer, every message after it is an input
+// http's goroutine, kept for as long as the socket is open
+func (s *State) NewWebSocketPlayer(writer http.ResponseWriter, request *http.Request) {
+	conn, err := upgrader.Upgrade(writer, request, nil)
+	if err != nil {
+		s.Log.Warning("Could not upgrade to a websocket: %v", err)
+		return // Upgrade has already replied
+	}
+
+	input := map[string]string{}
+	_ = conn.SetReadDeadline(time.Now().Add(webSocketHandshakeTimeout))
+	err = conn.ReadJSON(&input)
+	if err == nil && input["token"] == "" {
+		err = errors.New("No token")
+	}
+
+	var player *Player
+	if err == nil {
+		player, err = s.PlayerForToken(input["token"], input["name"])
+	}
+
+	if err != nil {
+		reason := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error())
+		_ = conn.WriteControl(websocket.CloseMessage, reason, time.Now().Add(time.Second))
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	transport := NewWebSocketTransport(conn)
+	if !s.OnBoardPlayer(player, transport) {
+		_ = transport.Close()
+		return
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			s.PlayerConnectionStateChanged(player, transport, false)
+			_ = transport.Close()
+			return
+		}
+		s.HandlePlayerInput(data, player, transport)
+	}
+}
+
 func (s *State) SetupRTCForPlayer(player *Player, offer string) (string, error) {
 	peerConnection, err := webrtc.New(s.InitialConfig.RTCSettings)
 	if err != nil {
 		return "", err
 	}
 
-	peerConnection.OnICEConnectionStateChange(func(connectionState ice.ConnectionState) { // TODO this handles disconnects
+	transport := &RTCTransport{Peer: peerConnection}
+
+	peerConnection.OnICEConnectionStateChange(func(connectionState ice.ConnectionState) {
 		s.Log.Info("ICE Connection State has changed: %s\n", connectionState.String())
+		if connected, known := iceConnected(connectionState); known {
+			s.PlayerConnectionStateChanged(player, transport, connected)
+		}
+	})
+
+	frames, err := NewFramesChannel(peerConnection)
+	if err != nil {
+		return "", err
+	}
+
+	frames.OnOpen(func() {
+		transport.AddChannel(frames)
+	})
+
+	frames.OnMessage(func(payload datachannel.Payload) { // Inputs are accepted on either channel
+		s.HandlePlayerInput(payloadBytes(payload), player, transport)
 	})
 
 	peerConnection.OnDataChannel(func(d *webrtc.RTCDataChannel) { // Called on a fresh goroutine (from the one for DC's)
 
 		// goroutine
+		// Inputs are only listened for once the player is on this transport, a reconnecting player's sequence has been reset by then
 		d.OnOpen(func() { // Called from the DC listen goroutine
-			s.OnBoardPlayer(player, d)
-		})
-
-		d.OnMessage(func(payload datachannel.Payload) { // Called from third goroutine
-			s.HandlePlayerInput(payload, player)
+			if transport.AddChannel(d) && !s.OnBoardPlayer(player, transport) {
+				go func() {
+					_ = transport.Close()
+				}()
+				return
+			}
+
+			d.OnMessage(func(payload datachannel.Payload) { // Called from third goroutine
+				s.HandlePlayerInput(payloadBytes(payload), player, transport)
+			})
 		})
 	})
 
@@ -116,29 +188,36 @@ func (s *State) CreateAnswer(offer64 string, peerConnection *webrtc.RTCPeerConne
 	return base64.StdEncoding.EncodeToString(jsonAnswer), nil
 }
 
-func (s *State) OnBoardPlayer(p *Player, d *webrtc.RTCDataChannel) {
+// Spawns a player once their transport is up, or hands an already spawned snake over to it
+// Returns false if the player can't play, the caller should hang up
+func (s *State) OnBoardPlayer(p *Player, t Transport) bool {
 	s.Lock()
 	defer s.Unlock()
 
-	p.Connection = d
+	if p.Connection != nil { // Already spawned, this is a new connection for the same snake
+		return s.ReattachPlayer(p, t)
+	}
+
+	if s.LobbyClosed { // Their token was already marked as a no show
+		s.Log.Warning("%v connected after the lobby closed", p.Name)
+		return false
+	}
+
+	p.Connection = t
+	p.Connected = true
 	s.SpawnPlayer(p)
 	s.PlayerCount++
 	s.TokenConsumed(p.Token)
-	if s.PlayerCount == s.SignupCount && s.Running == false {
-		go func() {
-			s.StartGame()
-		}()
+	if s.PlayerCount == s.SignupCount {
+		s.StartGame()
 	}
+	return true
 }
 
 func (s *State) SpawnPlayer(newPlayer *Player) {
-	snake := &SnakeNode{}
-
-	newPlayer.Snake = snake
-	snake.Player = newPlayer
-	s.World.ActivePlayers[newPlayer] = snake
-
-	s.AddNewSnakeToWorld(snake)
+	newPlayer.ID = s.Game.AddPlayer()
+	s.Players[newPlayer.ID] = newPlayer
+	s.Replay.RecordSpawn(s.Game, newPlayer.ID, newPlayer.Name, hash(newPlayer.Token), newPlayer.Bot != nil)
 }
 
 func (s *State) NewSpectator(writer http.ResponseWriter, request *http.Request) {
@@ -183,25 +262,47 @@ func (s *State) SetupRTCForSpectator(spectator *Spectator, offer string) (string
 		s.Log.Info("ICE Connection State has changed: %s\n", connectionState.String())
 	})
 
+	transport := &RTCTransport{Peer: peerConnection}
+
 	peerConnection.OnDataChannel(func(d *webrtc.RTCDataChannel) { // Called on a fresh goroutine (from the one for DC's)
 
 		// goroutine
 		d.OnOpen(func() { // Called from the DC listen goroutine
-			s.OnBoardSpectator(spectator, d)
+			if transport.AddChannel(d) {
+				s.OnBoardSpectator(spectator, transport)
+			}
 		})
 
 		d.OnMessage(func(payload datachannel.Payload) { // Called from third goroutine
-			s.HandleSpectatorInput(payload, spectator)
+			s.HandleSpectatorInput(payloadBytes(payload), spectator)
 		})
 	})
 
 	return s.CreateAnswer(offer, peerConnection)
 }
 
-func (s *State) OnBoardSpectator(spectator *Spectator, datachannel *webrtc.RTCDataChannel) {
+func (s *State) OnBoardSpectator(spectator *Spectator, t Transport) {
 
 }
 
+// The player a token plays as, the snake it already has when reconnecting or a new player when it's paid
+func (s *State) PlayerForToken(token, name string) (*Player, error) {
+	player, reconnecting := s.ReconnectingPlayer(token)
+	if reconnecting {
+		return player, nil
+	}
+
+	if !s.tokenIsValid(token) {
+		return nil, errors.New("Invalid Token")
+	}
+
+	return &Player{
+		Token: token,
+		Name:  name,
+		Input: &Input{ZoomLevel: s.InitialConfig.DefaultZoom},
+	}, nil
+}
+
 func (s *State) tokenIsValid(token string) bool {
 	status, _ := s.PlayerRedis.HGet(token, "status").Result()
 	if status == "paid" {
@@ -210,6 +311,52 @@ func (s *State) tokenIsValid(token string) bool {
 	return false
 }
 
+// The player a token belongs to if it's rejoining this game with its snake still alive
+func (s *State) ReconnectingPlayer(token string) (*Player, bool) {
+	status, _ := s.PlayerRedis.HGet(token, "status").Result()
+	game, _ := s.PlayerRedis.HGet(token, "game").Result()
+	if status != "in game" || game != s.GameID {
+		return nil, false
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for id, player := range s.Players {
+		if _, alive := s.Game.ActivePlayers[id]; alive && player.Token == token && !player.Forfeit {
+			return player, true
+		}
+	}
+
+	return nil, false
+}
+
+// Swaps a spawned player over to their new transport and hangs up the old one, call with the lock held
+// The new client counts its inputs from 1 again, HandlePlayerInput ignores the old one's from here on
+func (s *State) ReattachPlayer(p *Player, t Transport) bool {
+	if p.Forfeit {
+		s.Log.Warning("%v reconnected after forfeiting", p.Name)
+		return false
+	}
+
+	if p.Connection == t { // Already attached, nothing to reset or hang up
+		return true
+	}
+
+	p.InputSequence = 0
+	p.AckedTick = 0 // The new client has none of the frames sent so far
+
+	old := p.Connection
+	p.Connection = t
+	p.Connected = true
+	s.Log.Info("%v reconnected with a new connection", p.Name)
+
+	go func() {
+		_ = old.Close()
+	}()
+	return true
+}
+
 func (s *State) TokenConsumed(token string) {
 	unconfirmed, _ := s.PlayerRedis.HGet(token, "unconfirmed").Result()
 	incr, _ := strconv.ParseInt(unconfirmed, 10, 64) // incr must be base 10 int64
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/moneygames-io/gameserver/protocol"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func input(sequence uint32, direction int) []byte {
+	return protocol.EncodeInput(protocol.Input{Direction: direction, Zoom: 10, Sequence: sequence})
+}
+
+func TestPlayerForToken(t *testing.T) {
+	s, done := newTestState(t, 3)
+	defer done()
+
+	_, err := s.PlayerForToken("unpaid", "x")
+	assert.NotNil(t, err)
+
+	signUp(s, "a")
+	player, err := s.PlayerForToken("a", "Ann")
+	assert.Nil(t, err)
+	assert.Equal(t, "Ann", player.Name)
+	assert.Nil(t, player.Connection)
+	assert.Equal(t, s.InitialConfig.DefaultZoom, player.Input.ZoomLevel)
+
+	assert.True(t, s.OnBoardPlayer(player, &recordingTransport{}))
+	assert.Equal(t, "in game", status(s, "a"))
+
+	// The token now plays as its snake rather than as someone new
+	again, err := s.PlayerForToken("a", "Ann")
+	assert.Nil(t, err)
+	assert.Equal(t, player, again)
+}
+
+func TestReconnectingPlayer(t *testing.T) {
+	s, done := newTestState(t, 3)
+	defer done()
+
+	transports := lobby(t, s, []string{"a", "b", "c"}, 2)
+	_, reconnecting := s.ReconnectingPlayer("c")
+	assert.False(t, reconnecting)
+
+	player, reconnecting := s.ReconnectingPlayer("a")
+	assert.True(t, reconnecting)
+	assert.Equal(t, "a", player.Token)
+
+	// In another game
+	s.PlayerRedis.HSet("b", "game", "10001")
+	_, reconnecting = s.ReconnectingPlayer("b")
+	assert.False(t, reconnecting)
+
+	// Gave up their snake
+	player.Forfeit = true
+	_, reconnecting = s.ReconnectingPlayer("a")
+	assert.False(t, reconnecting)
+	assert.False(t, s.OnBoardPlayer(player, &recordingTransport{}))
+	assert.False(t, transports[0].isClosed())
+}
+
+func TestReattachPlayer(t *testing.T) {
+	s, done := newTestState(t, 3)
+	defer done()
+
+	old := lobby(t, s, []string{"a", "b", "c"}, 2)[0]
+	player := s.Players[0]
+
+	s.HandlePlayerInput(input(7, 1), player, old)
+	assert.Equal(t, uint32(7), player.InputSequence)
+	assert.Equal(t, 1, player.Input.Direction)
+
+	s.PlayerConnectionStateChanged(player, old, false)
+	assert.False(t, player.Connected)
+
+	// Inputs the new client sends before it's attached are ignored too, it's not the player's transport yet
+	fresh := &recordingTransport{}
+	s.HandlePlayerInput(input(1, 2), player, fresh)
+	assert.Equal(t, 1, player.Input.Direction)
+
+	assert.True(t, s.OnBoardPlayer(player, fresh))
+	assert.True(t, player.Connected)
+	assert.Equal(t, fresh, player.Connection)
+	assert.Equal(t, uint32(0), player.InputSequence)
+
+	// The new client counts from 1 again
+	s.HandlePlayerInput(input(1, 3), player, fresh)
+	assert.Equal(t, uint32(1), player.InputSequence)
+	assert.Equal(t, 3, player.Input.Direction)
+
+	// Whatever the old transport still delivers is ignored
+	s.HandlePlayerInput(input(8, 0), player, old)
+	assert.Equal(t, uint32(1), player.InputSequence)
+	assert.Equal(t, 3, player.Input.Direction)
+
+	s.PlayerConnectionStateChanged(player, old, false)
+	assert.True(t, player.Connected)
+
+	for i := 0; i < 100 && !old.isClosed(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, old.isClosed())
+	assert.Equal(t, 2, s.PlayerCount)
+
+	// Onboarding again over the attached transport leaves it as it is
+	assert.True(t, s.OnBoardPlayer(player, fresh))
+	assert.Equal(t, uint32(1), player.InputSequence)
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, fresh.isClosed())
+}
@@ -0,0 +1,262 @@
+package protocol
+
+import (
+	"sort"
+)
+
+// A frame sent as the changes from an earlier frame the client acknowledged
+// The client rebuilds the frame's View with Apply
+type Delta struct {
+	// Tick of the frame this is relative to
+	Baseline int32
+
+	TopLeftRow   int32
+	TopLeftCol   int32
+	ViewportSize int32
+	MapSize      int32
+
+	// As in Frame
+	InputSequence uint32
+	TicksLeft     int32
+	Zone          Zone
+
+	// Tiles inside the viewport that are new or hold something different than in the baseline
+	Snakes []SnakeTile
+	Food   []FoodTile
+
+	// Tiles inside the viewport that were occupied in the baseline and are empty now
+	Cleared []EmptyTile
+
+	// Sent whole, it's small and changes every tick anyway
+	Leaders []Leader
+}
+
+type EmptyTile struct {
+	Row int32
+	Col int32
+}
+
+func (*Delta) Type() MessageType { return DeltaMessage }
+
+// A frame as individual tiles in row major order, what deltas are worked out against
+type View struct {
+	TopLeftRow    int32
+	TopLeftCol    int32
+	ViewportSize  int32
+	MapSize       int32
+	InputSequence uint32
+	TicksLeft     int32
+	Zone          Zone
+	Snakes        []SnakeTile
+	Food          []FoodTile
+	Leaders       []Leader
+}
+
+func (f *Frame) View() *View {
+	view := &View{
+		TopLeftRow:    f.TopLeftRow,
+		TopLeftCol:    f.TopLeftCol,
+		ViewportSize:  f.ViewportSize,
+		MapSize:       f.MapSize,
+		InputSequence: f.InputSequence,
+		TicksLeft:     f.TicksLeft,
+		Zone:          f.Zone,
+		Food:          append([]FoodTile(nil), f.Food...),
+		Leaders:       f.Leaders,
+	}
+
+	for _, segment := range f.Snakes {
+		view.Snakes = append(view.Snakes, segment.Tiles()...)
+	}
+
+	sort.Slice(view.Snakes, func(i, j int) bool {
+		return rowMajor(view.Snakes[i].Row, view.Snakes[i].Col, view.Snakes[j].Row, view.Snakes[j].Col)
+	})
+	sort.Slice(view.Food, func(i, j int) bool {
+		return rowMajor(view.Food[i].Row, view.Food[i].Col, view.Food[j].Row, view.Food[j].Col)
+	})
+
+	return view
+}
+
+func rowMajor(rowA, colA, rowB, colB int32) bool {
+	return rowA < rowB || (rowA == rowB && colA < colB)
+}
+
+type tileKey struct {
+	row int32
+	col int32
+}
+
+// What occupies a tile, a snake's player hash or food
+type tile struct {
+	food   bool
+	player int32
+}
+
+func (v *View) tiles() map[tileKey]tile {
+	tiles := make(map[tileKey]tile, len(v.Snakes)+len(v.Food))
+	for _, snake := range v.Snakes {
+		tiles[tileKey{snake.Row, snake.Col}] = tile{player: snake.Player}
+	}
+	for _, food := range v.Food {
+		tiles[tileKey{food.Row, food.Col}] = tile{food: true}
+	}
+	return tiles
+}
+
+func (v *View) inView(row, col int32) bool {
+	return row >= v.TopLeftRow && row < v.TopLeftRow+v.ViewportSize &&
+		col >= v.TopLeftCol && col < v.TopLeftCol+v.ViewportSize
+}
+
+// The changes that turn baseline, sent on tick baselineTick, into current
+func Diff(baselineTick int, baseline, current *View) *Delta {
+	delta := &Delta{
+		Baseline:      int32(baselineTick),
+		TopLeftRow:    current.TopLeftRow,
+		TopLeftCol:    current.TopLeftCol,
+		ViewportSize:  current.ViewportSize,
+		MapSize:       current.MapSize,
+		InputSequence: current.InputSequence,
+		TicksLeft:     current.TicksLeft,
+		Zone:          current.Zone,
+		Leaders:       current.Leaders,
+	}
+
+	before := baseline.tiles()
+
+	for _, snake := range current.Snakes {
+		old, present := before[tileKey{snake.Row, snake.Col}]
+		if !present || old.food || old.player != snake.Player {
+			delta.Snakes = append(delta.Snakes, snake)
+		}
+	}
+
+	for _, food := range current.Food {
+		old, present := before[tileKey{food.Row, food.Col}]
+		if !present || !old.food {
+			delta.Food = append(delta.Food, food)
+		}
+	}
+
+	after := current.tiles()
+	for key := range before {
+		if _, present := after[key]; !present && current.inView(key.row, key.col) {
+			delta.Cleared = append(delta.Cleared, EmptyTile{Row: key.row, Col: key.col})
+		}
+	}
+
+	sort.Slice(delta.Cleared, func(i, j int) bool {
+		return rowMajor(delta.Cleared[i].Row, delta.Cleared[i].Col, delta.Cleared[j].Row, delta.Cleared[j].Col)
+	})
+
+	return delta
+}
+
+// Rebuilds the view a delta describes, tiles of the baseline outside the new viewport are dropped
+func Apply(baseline *View, delta *Delta) *View {
+	view := &View{
+		TopLeftRow:    delta.TopLeftRow,
+		TopLeftCol:    delta.TopLeftCol,
+		ViewportSize:  delta.ViewportSize,
+		MapSize:       delta.MapSize,
+		InputSequence: delta.InputSequence,
+		TicksLeft:     delta.TicksLeft,
+		Zone:          delta.Zone,
+		Leaders:       delta.Leaders,
+	}
+
+	tiles := baseline.tiles()
+	for _, empty := range delta.Cleared {
+		delete(tiles, tileKey{empty.Row, empty.Col})
+	}
+	for _, snake := range delta.Snakes {
+		tiles[tileKey{snake.Row, snake.Col}] = tile{player: snake.Player}
+	}
+	for _, food := range delta.Food {
+		tiles[tileKey{food.Row, food.Col}] = tile{food: true}
+	}
+
+	keys := make([]tileKey, 0, len(tiles))
+	for key := range tiles {
+		if view.inView(key.row, key.col) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return rowMajor(keys[i].row, keys[i].col, keys[j].row, keys[j].col)
+	})
+
+	for _, key := range keys {
+		if tiles[key].food {
+			view.Food = append(view.Food, FoodTile{Row: key.row, Col: key.col})
+		} else {
+			view.Snakes = append(view.Snakes, SnakeTile{Player: tiles[key].player, Row: key.row, Col: key.col})
+		}
+	}
+
+	return view
+}
+
+func (delta *Delta) encode(e *encoder) {
+	e.int32(delta.Baseline)
+	e.int32(delta.TopLeftRow)
+	e.int32(delta.TopLeftCol)
+	e.int32(delta.ViewportSize)
+	e.int32(delta.MapSize)
+	e.int32(int32(delta.InputSequence))
+	e.int32(delta.TicksLeft)
+	encodeZone(e, delta.Zone)
+
+	e.int(len(delta.Snakes))
+	for _, snake := range delta.Snakes {
+		e.int32(snake.Player)
+		e.int32(snake.Row)
+		e.int32(snake.Col)
+	}
+
+	e.int(len(delta.Food))
+	for _, food := range delta.Food {
+		e.int32(food.Row)
+		e.int32(food.Col)
+	}
+
+	e.int(len(delta.Cleared))
+	for _, empty := range delta.Cleared {
+		e.int32(empty.Row)
+		e.int32(empty.Col)
+	}
+
+	encodeLeaders(e, delta.Leaders)
+}
+
+func (delta *Delta) decode(d *decoder) error {
+	delta.Baseline = d.int32()
+	delta.TopLeftRow = d.int32()
+	delta.TopLeftCol = d.int32()
+	delta.ViewportSize = d.int32()
+	delta.MapSize = d.int32()
+	delta.InputSequence = uint32(d.int32())
+	delta.TicksLeft = d.int32()
+	delta.Zone = decodeZone(d)
+
+	delta.Snakes = make([]SnakeTile, d.count(3))
+	for i := range delta.Snakes {
+		delta.Snakes[i] = SnakeTile{Player: d.int32(), Row: d.int32(), Col: d.int32()}
+	}
+
+	delta.Food = make([]FoodTile, d.count(2))
+	for i := range delta.Food {
+		delta.Food[i] = FoodTile{Row: d.int32(), Col: d.int32()}
+	}
+
+	delta.Cleared = make([]EmptyTile, d.count(2))
+	for i := range delta.Cleared {
+		delta.Cleared[i] = EmptyTile{Row: d.int32(), Col: d.int32()}
+	}
+
+	delta.Leaders = decodeLeaders(d)
+	return d.err
+}
@@ -0,0 +1,52 @@
+package protocol
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiffApply(t *testing.T) {
+	leaders := []Leader{{Player: 7, Length: 2, Name: "a"}}
+
+	baseline := &Frame{
+		TopLeftRow: 0, TopLeftCol: 0, ViewportSize: 4, MapSize: 10,
+		Snakes:  []SnakeSegment{{Player: 7, Row: 1, Col: 1, Runs: []Run{{Direction: Down, Length: 1}}}},
+		Food:    []FoodTile{{Row: 0, Col: 3}, {Row: 3, Col: 0}},
+		Leaders: leaders,
+	}
+
+	// Head moved right onto food, tail followed, viewport shifted right by one
+	current := &Frame{
+		TopLeftRow: 0, TopLeftCol: 1, ViewportSize: 4, MapSize: 10,
+		Snakes:  []SnakeSegment{{Player: 7, Row: 1, Col: 2, Runs: []Run{{Direction: Left, Length: 1}}}},
+		Food:    []FoodTile{{Row: 0, Col: 3}, {Row: 3, Col: 4}},
+		Leaders: leaders,
+	}
+
+	delta := Diff(5, baseline.View(), current.View())
+	assert.Equal(t, int32(5), delta.Baseline)
+	assert.Equal(t, []SnakeTile{{Player: 7, Row: 1, Col: 2}}, delta.Snakes)
+	assert.Equal(t, []FoodTile{{Row: 3, Col: 4}}, delta.Food)
+	assert.Equal(t, []EmptyTile{{Row: 2, Col: 1}}, delta.Cleared)
+
+	assert.Equal(t, current.View(), Apply(baseline.View(), delta))
+
+	_, decoded, err := Decode(Encode(&Buffer{}, 6, delta))
+	assert.Nil(t, err)
+	assert.Equal(t, delta, decoded)
+}
+
+func TestFrame_View(t *testing.T) {
+	frame := &Frame{
+		ViewportSize: 4,
+		Snakes: []SnakeSegment{
+			{Player: 1, Row: 2, Col: 2, Runs: []Run{{Direction: Up, Length: 2}}},
+			{Player: 2, Row: 1, Col: 0},
+		},
+		Food: []FoodTile{{Row: 3, Col: 3}, {Row: 0, Col: 0}},
+	}
+
+	view := frame.View()
+	assert.Equal(t, []SnakeTile{{Player: 1, Row: 0, Col: 2}, {Player: 2, Row: 1, Col: 0}, {Player: 1, Row: 1, Col: 2}, {Player: 1, Row: 2, Col: 2}}, view.Snakes)
+	assert.Equal(t, []FoodTile{{Row: 0, Col: 0}, {Row: 3, Col: 3}}, view.Food)
+}
@@ -0,0 +1,76 @@
+// Package protocol defines the messages the server sends to clients and how they are laid out
+//
+// A message is a sequence of little-endian int32 words so browsers can read it as an Int32Array.
+// Every message starts with a four word header:
+//
+//	Version  always Version, clients should drop messages with one they don't know
+//	Type     one of the MessageType constants
+//	Length   how many words follow the header
+//	Tick     engine tick the message was built on, 0 outside the game loop
+//
+// Strings are a word holding the number of runes followed by one word per rune.
+// The body depends on Type:
+//
+// FrameMessage, what one player can see this tick:
+//
+//	TopLeftRow, TopLeftCol  map coordinate of the viewport's top left tile
+//	ViewportSize            tiles along each side of the viewport
+//	MapSize                 tiles along each side of the map
+//	InputSequence           Sequence of the player's last input applied, 0 before the first
+//	TicksLeft               ticks until the match ends, -1 in modes that don't end on time
+//	ZoneRow, ZoneCol        map coordinate of the safe zone's top left tile, anything outside it kills like a wall
+//	ZoneSize                tiles along each side of the safe zone, MapSize until it starts shrinking
+//	SegmentCount            then SegmentCount x segment, covering every snake tile in view
+//	FoodCount               then FoodCount x Gap, one for every food tile in view
+//	LeaderCount             then LeaderCount x leader, the leaderboard plus the player if they're not on it
+//
+// A segment is an unbroken stretch of one snake, walked from the end nearest its head:
+//
+//	Player, Row, Col        player hash and where the stretch starts
+//	RunCount                then RunCount x (Length<<2 | Direction), Length tiles each a step in Direction from the last
+//
+// Directions are 0 up, 1 right, 2 down, 3 left. Food tiles are numbered row major from the viewport's
+// top left tile, and each Gap is how far on a food tile is from the one before it, the first from -1.
+//
+// A leader is:
+//
+//	Player, Row, Col        player hash and where their head is
+//	Length                  how long their snake is
+//	Spectators              how many spectators are watching them
+//	Flags                   FlagBot if the server controls the snake
+//	Name                    string
+//
+// DeltaMessage, a frame as changes from the earlier frame sent on tick Baseline, see Apply:
+//
+//	Baseline                tick of the frame the client acknowledged
+//	TopLeftRow, TopLeftCol  as in FrameMessage
+//	ViewportSize, MapSize   as in FrameMessage
+//	InputSequence           as in FrameMessage
+//	TicksLeft               as in FrameMessage
+//	ZoneRow, ZoneCol        as in FrameMessage
+//	ZoneSize                as in FrameMessage
+//	SnakeCount              then SnakeCount x (Player, Row, Col) for snake tiles that are new or changed
+//	FoodCount               then FoodCount x (Row, Col) for food tiles that are new or changed
+//	ClearedCount            then ClearedCount x (Row, Col) for tiles in view that have emptied
+//	LeaderCount             then LeaderCount x leader, as in FrameMessage
+//
+// Tiles of the baseline that fall outside the new viewport are dropped.
+//
+// WonMessage: Pot as a string
+//
+// KillMessage, someone died:
+//
+//	Cause                   one of the KillCause constants
+//	Victim, VictimName      player hash and name string of who died
+//	KillerCount             then KillerCount x (Player, Name, Kills) for who the kill counts for, 0 or 1 of them
+//
+// LostMessage, AbortedMessage: no body
+//
+// Clients send their inputs in a separate fixed size layout, see DecodeInput. They send one whenever the
+// player steers and one after every frame they receive, acknowledging it, even while the input is unchanged.
+//
+// Over WebRTC the client opens a data channel labelled "control" and the server opens one labelled
+// "frames", both ordered and reliable. FrameMessage and DeltaMessage go on frames, or on control until
+// frames is open, everything else on control. Inputs are accepted on either. Clients shouldn't count on
+// frames arriving in order or at all, the server may stop retransmitting them.
+package protocol
@@ -0,0 +1,88 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Bumped whenever the layout of Input changes, independently of Version
+const InputVersion = 1
+
+// Bytes in an input message
+const InputSize = 16
+
+// What a client sends whenever the player steers and after every frame it receives, see DecodeInput for the layout
+// The ones after frames repeat the player's current input, they're how the client acknowledges frames
+// A client that goes straight without sending them is never sent anything but keyframes
+type Input struct {
+	Direction int
+	Zoom      int
+	Sprinting bool
+
+	// Counts up from 1 with every input the client sends, frames echo the last one the server applied
+	Sequence uint32
+
+	// Milliseconds on the client's clock when it was sent, only meaningful to the client
+	Timestamp uint32
+
+	// Tick of the latest frame the client has, 0 if it has none, deltas are built against it
+	AckedTick int
+}
+
+// Reads an input message, rejecting anything that isn't exactly one well formed input
+//
+//	byte 0       InputVersion
+//	byte 1       Direction, 0 up, 1 right, 2 down, 3 left
+//	byte 2       Zoom
+//	byte 3       1 while sprinting, otherwise 0
+//	bytes 4-7    Sequence
+//	bytes 8-11   Timestamp
+//	bytes 12-15  AckedTick
+//
+// Multi-byte fields are little-endian and unsigned
+func DecodeInput(data []byte) (Input, error) {
+	if len(data) != InputSize {
+		return Input{}, fmt.Errorf("input of %v bytes, expected %v", len(data), InputSize)
+	}
+
+	if data[0] != InputVersion {
+		return Input{}, fmt.Errorf("unsupported input version %v", data[0])
+	}
+
+	if data[1] > Left {
+		return Input{}, fmt.Errorf("unknown direction %v", data[1])
+	}
+
+	if data[3] > 1 {
+		return Input{}, fmt.Errorf("sprint flag %v isn't 0 or 1", data[3])
+	}
+
+	acked := binary.LittleEndian.Uint32(data[12:16])
+	if acked > 1<<31-1 {
+		return Input{}, fmt.Errorf("acked tick %v out of range", acked)
+	}
+
+	return Input{
+		Direction: int(data[1]),
+		Zoom:      int(data[2]),
+		Sprinting: data[3] == 1,
+		Sequence:  binary.LittleEndian.Uint32(data[4:8]),
+		Timestamp: binary.LittleEndian.Uint32(data[8:12]),
+		AckedTick: int(acked),
+	}, nil
+}
+
+// Lays out an input the way DecodeInput reads it, for clients and tests written in Go
+func EncodeInput(input Input) []byte {
+	data := make([]byte, InputSize)
+	data[0] = InputVersion
+	data[1] = byte(input.Direction)
+	data[2] = byte(input.Zoom)
+	if input.Sprinting {
+		data[3] = 1
+	}
+	binary.LittleEndian.PutUint32(data[4:8], input.Sequence)
+	binary.LittleEndian.PutUint32(data[8:12], input.Timestamp)
+	binary.LittleEndian.PutUint32(data[12:16], uint32(input.AckedTick))
+	return data
+}
@@ -0,0 +1,34 @@
+package protocol
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecodeInput(t *testing.T) {
+	input := Input{Direction: Left, Zoom: 12, Sprinting: true, Sequence: 1<<32 - 1, Timestamp: 123456, AckedTick: 40}
+
+	decoded, err := DecodeInput(EncodeInput(input))
+	assert.Nil(t, err)
+	assert.Equal(t, input, decoded)
+}
+
+func TestDecodeInput_Invalid(t *testing.T) {
+	data := EncodeInput(Input{Direction: Up, Zoom: 10, Sequence: 1})
+
+	_, err := DecodeInput(data[:InputSize-1])
+	assert.NotNil(t, err)
+
+	_, err = DecodeInput(append(data, 0))
+	assert.NotNil(t, err)
+
+	_, err = DecodeInput(nil)
+	assert.NotNil(t, err)
+
+	for i, value := range map[int]byte{0: InputVersion + 1, 1: Left + 1, 3: 2, 15: 0x80} {
+		bad := append([]byte{}, data...)
+		bad[i] = value
+		_, err = DecodeInput(bad)
+		assert.NotNil(t, err, "byte %v = %v", i, value)
+	}
+}
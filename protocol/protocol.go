@@ -0,0 +1,391 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Bumped whenever the layout of any message changes
+const Version = 6
+
+// Bytes in a word and in a header
+const (
+	WordSize   = 4
+	HeaderSize = 4 * WordSize
+)
+
+type MessageType int32
+
+const (
+	FrameMessage MessageType = iota + 1
+	WonMessage
+	LostMessage
+	AbortedMessage
+	DeltaMessage
+	KillMessage
+)
+
+// Bits of Leader.Flags
+const (
+	FlagBot = 1 << iota
+)
+
+type Header struct {
+	Version int32
+	Type    MessageType
+	Length  int32
+	Tick    int32
+}
+
+// A message body, one of the types below
+type Message interface {
+	Type() MessageType
+	encode(e *encoder)
+	decode(d *decoder) error
+}
+
+type Frame struct {
+	TopLeftRow   int32
+	TopLeftCol   int32
+	ViewportSize int32
+	MapSize      int32
+
+	// Sequence of the last Input from this player the frame reflects, 0 before the first one
+	InputSequence uint32
+
+	// Ticks until the match ends, -1 in modes that don't end on time
+	TicksLeft int32
+
+	// The square of the map it's safe to be in, for drawing on the minimap
+	Zone Zone
+
+	// Every snake node in view, food has to be in view too as it's sent relative to the viewport
+	Snakes []SnakeSegment
+	Food   []FoodTile
+
+	Leaders []Leader
+}
+
+// A square of the map, Size tiles along each side from Row, Col
+type Zone struct {
+	Row  int32
+	Col  int32
+	Size int32
+}
+
+type SnakeTile struct {
+	Player int32
+	Row    int32
+	Col    int32
+}
+
+type FoodTile struct {
+	Row int32
+	Col int32
+}
+
+type Leader struct {
+	Player     int32
+	Row        int32
+	Col        int32
+	Length     int32
+	Spectators int32
+	Flags      int32
+	Name       string
+}
+
+type Won struct {
+	Pot string
+}
+
+// What a snake died of
+type KillCause int32
+
+const (
+	KillWall KillCause = iota + 1
+	KillSelf
+	KillBody
+	KillHeadOn
+	KillForfeit
+)
+
+// Someone died, everyone is sent one for the kill feed
+type Kill struct {
+	Cause      KillCause
+	Victim     int32
+	VictimName string
+
+	// Who the kill counts for, at most one, none for walls, their own body, forfeits and head on ties
+	Killers []Killer
+}
+
+type Killer struct {
+	Player int32
+	Name   string
+
+	// How many kills they have now, this one included
+	Kills int32
+}
+
+type Lost struct{}
+
+type Aborted struct{}
+
+func (*Frame) Type() MessageType   { return FrameMessage }
+func (*Won) Type() MessageType     { return WonMessage }
+func (*Lost) Type() MessageType    { return LostMessage }
+func (*Aborted) Type() MessageType { return AbortedMessage }
+func (*Kill) Type() MessageType    { return KillMessage }
+
+// Holds encoded messages, reusing its memory from one Encode to the next
+type Buffer struct {
+	data []byte
+}
+
+// The last message encoded, only valid until the next Encode
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// Lays out a message with its header into b, replacing what was there
+func Encode(b *Buffer, tick int, message Message) []byte {
+	b.data = b.data[:0]
+	e := &encoder{buffer: b}
+
+	e.int32(Version)
+	e.int32(int32(message.Type()))
+	e.int32(0) // Length, filled in below
+	e.int32(int32(tick))
+
+	message.encode(e)
+	binary.LittleEndian.PutUint32(b.data[2*WordSize:], uint32((len(b.data)-HeaderSize)/WordSize))
+
+	return b.data
+}
+
+// Reads one message, rejecting anything truncated, padded or from another version
+func Decode(data []byte) (Header, Message, error) {
+	if len(data) < HeaderSize {
+		return Header{}, nil, errors.New("message shorter than a header")
+	}
+
+	if len(data)%WordSize != 0 {
+		return Header{}, nil, fmt.Errorf("message of %v bytes isn't whole words", len(data))
+	}
+
+	d := &decoder{data: data}
+	header := Header{
+		Version: d.int32(),
+		Type:    MessageType(d.int32()),
+		Length:  d.int32(),
+		Tick:    d.int32(),
+	}
+
+	if header.Version != Version {
+		return header, nil, fmt.Errorf("unsupported version %v", header.Version)
+	}
+
+	if int(header.Length) != d.words() {
+		return header, nil, fmt.Errorf("header says %v words, body has %v", header.Length, d.words())
+	}
+
+	var message Message
+	switch header.Type {
+	case FrameMessage:
+		message = &Frame{}
+	case WonMessage:
+		message = &Won{}
+	case LostMessage:
+		message = &Lost{}
+	case AbortedMessage:
+		message = &Aborted{}
+	case DeltaMessage:
+		message = &Delta{}
+	case KillMessage:
+		message = &Kill{}
+	default:
+		return header, nil, fmt.Errorf("unknown message type %v", header.Type)
+	}
+
+	err := message.decode(d)
+	if err == nil && d.words() != 0 {
+		err = fmt.Errorf("%v words left over", d.words())
+	}
+
+	return header, message, err
+}
+
+func (f *Frame) encode(e *encoder) {
+	e.int32(f.TopLeftRow)
+	e.int32(f.TopLeftCol)
+	e.int32(f.ViewportSize)
+	e.int32(f.MapSize)
+	e.int32(int32(f.InputSequence))
+	e.int32(f.TicksLeft)
+	encodeZone(e, f.Zone)
+
+	encodeSegments(e, f.Snakes)
+	f.encodeFood(e)
+	encodeLeaders(e, f.Leaders)
+}
+
+func encodeZone(e *encoder, zone Zone) {
+	e.int32(zone.Row)
+	e.int32(zone.Col)
+	e.int32(zone.Size)
+}
+
+func decodeZone(d *decoder) Zone {
+	return Zone{Row: d.int32(), Col: d.int32(), Size: d.int32()}
+}
+
+func encodeLeaders(e *encoder, leaders []Leader) {
+	e.int(len(leaders))
+	for _, leader := range leaders {
+		e.int32(leader.Player)
+		e.int32(leader.Row)
+		e.int32(leader.Col)
+		e.int32(leader.Length)
+		e.int32(leader.Spectators)
+		e.int32(leader.Flags)
+		e.string(leader.Name)
+	}
+}
+
+func (f *Frame) decode(d *decoder) error {
+	f.TopLeftRow = d.int32()
+	f.TopLeftCol = d.int32()
+	f.ViewportSize = d.int32()
+	f.MapSize = d.int32()
+	f.InputSequence = uint32(d.int32())
+	f.TicksLeft = d.int32()
+	f.Zone = decodeZone(d)
+
+	f.Snakes = decodeSegments(d)
+	f.decodeFood(d)
+	f.Leaders = decodeLeaders(d)
+	return d.err
+}
+
+func decodeLeaders(d *decoder) []Leader {
+	leaders := make([]Leader, d.count(7))
+	for i := range leaders {
+		leaders[i] = Leader{
+			Player:     d.int32(),
+			Row:        d.int32(),
+			Col:        d.int32(),
+			Length:     d.int32(),
+			Spectators: d.int32(),
+			Flags:      d.int32(),
+			Name:       d.string(),
+		}
+	}
+	return leaders
+}
+
+func (w *Won) encode(e *encoder) {
+	e.string(w.Pot)
+}
+
+func (w *Won) decode(d *decoder) error {
+	w.Pot = d.string()
+	return d.err
+}
+
+func (k *Kill) encode(e *encoder) {
+	e.int32(int32(k.Cause))
+	e.int32(k.Victim)
+	e.string(k.VictimName)
+
+	e.int(len(k.Killers))
+	for _, killer := range k.Killers {
+		e.int32(killer.Player)
+		e.string(killer.Name)
+		e.int32(killer.Kills)
+	}
+}
+
+func (k *Kill) decode(d *decoder) error {
+	k.Cause = KillCause(d.int32())
+	k.Victim = d.int32()
+	k.VictimName = d.string()
+
+	k.Killers = make([]Killer, d.count(3))
+	for i := range k.Killers {
+		k.Killers[i] = Killer{Player: d.int32(), Name: d.string(), Kills: d.int32()}
+	}
+	return d.err
+}
+
+func (*Lost) encode(e *encoder)       {}
+func (*Lost) decode(d *decoder) error { return nil }
+
+func (*Aborted) encode(e *encoder)       {}
+func (*Aborted) decode(d *decoder) error { return nil }
+
+// Appends little-endian words to a Buffer
+type encoder struct {
+	buffer *Buffer
+}
+
+func (e *encoder) int32(v int32) {
+	u := uint32(v)
+	e.buffer.data = append(e.buffer.data, byte(u), byte(u>>8), byte(u>>16), byte(u>>24))
+}
+
+func (e *encoder) int(v int) {
+	e.int32(int32(v))
+}
+
+func (e *encoder) string(s string) {
+	runes := []rune(s)
+	e.int(len(runes))
+	for _, r := range runes {
+		e.int32(r)
+	}
+}
+
+// Reads little-endian words off the front, the first problem sticks in err and later reads return zeros
+type decoder struct {
+	data []byte
+	err  error
+}
+
+// Whole words left to read
+func (d *decoder) words() int {
+	return len(d.data) / WordSize
+}
+
+func (d *decoder) int32() int32 {
+	if len(d.data) < WordSize {
+		if d.err == nil {
+			d.err = errors.New("message ended early")
+		}
+		return 0
+	}
+
+	v := int32(binary.LittleEndian.Uint32(d.data))
+	d.data = d.data[WordSize:]
+	return v
+}
+
+// Reads a count of items that are at least size words each, refusing counts the message can't hold
+func (d *decoder) count(size int) int {
+	n := int(d.int32())
+	if n < 0 || n*size > d.words() {
+		if d.err == nil {
+			d.err = fmt.Errorf("count %v doesn't fit in the message", n)
+		}
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) string() string {
+	runes := make([]rune, d.count(1))
+	for i := range runes {
+		runes[i] = d.int32()
+	}
+	return string(runes)
+}
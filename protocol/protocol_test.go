@@ -0,0 +1,138 @@
+package protocol
+
+import (
+	"bytes"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var messages = map[string]Message{
+	"frame": &Frame{
+		TopLeftRow:    -3,
+		TopLeftCol:    7,
+		ViewportSize:  20,
+		MapSize:       500,
+		InputSequence: 1 << 31,
+		TicksLeft:     -1,
+		Zone:          Zone{Row: 0, Col: 0, Size: 500},
+		Snakes: []SnakeSegment{
+			{Player: -1, Row: 1, Col: 8, Runs: []Run{{Direction: Down, Length: 3}, {Direction: Right, Length: 1}}},
+			{Player: 'F', Row: -3, Col: 26, Runs: []Run{}},
+		},
+		Food: []FoodTile{{Row: -3, Col: 7}, {Row: 4, Col: 9}, {Row: 4, Col: 10}},
+		Leaders: []Leader{
+			{Player: -2, Row: 1, Col: 2, Length: 9, Spectators: 1, Name: "Añil"},
+			{Player: 3, Flags: FlagBot, Name: ""},
+		},
+	},
+	"delta": &Delta{
+		Baseline:      40,
+		TopLeftRow:    -3,
+		TopLeftCol:    8,
+		ViewportSize:  20,
+		MapSize:       500,
+		InputSequence: 12,
+		TicksLeft:     300,
+		Zone:          Zone{Row: 20, Col: 20, Size: 460},
+		Snakes:        []SnakeTile{{Player: -1, Row: 1, Col: 4}},
+		Food:          []FoodTile{},
+		Cleared:       []EmptyTile{{Row: 1, Col: 2}},
+		Leaders:       []Leader{{Player: -2, Length: 9, Name: "b"}},
+	},
+	"kill": &Kill{
+		Cause:      KillBody,
+		Victim:     -5,
+		VictimName: "a",
+		Killers:    []Killer{{Player: 6, Name: "Añil", Kills: 2}},
+	},
+	"won":     &Won{Pot: "12.5"},
+	"lost":    &Lost{},
+	"aborted": &Aborted{},
+}
+
+func TestEncodeDecode(t *testing.T) {
+	b := &Buffer{}
+
+	for name, message := range messages {
+		data := Encode(b, 42, message)
+
+		header, decoded, err := Decode(data)
+		assert.Nil(t, err, name)
+		assert.Equal(t, Header{Version: Version, Type: message.Type(), Length: int32((len(data) - HeaderSize) / WordSize), Tick: 42}, header, name)
+		assert.Equal(t, message, decoded, name)
+	}
+}
+
+// Pins the exact bytes so a layout change can't slip past clients unnoticed, run with -update after bumping Version
+func TestEncode_Golden(t *testing.T) {
+	b := &Buffer{}
+
+	for name, message := range messages {
+		data := Encode(b, 42, message)
+		path := filepath.Join("testdata", name+".golden")
+
+		if *update {
+			assert.Nil(t, ioutil.WriteFile(path, data, 0644))
+		}
+
+		golden, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.True(t, bytes.Equal(golden, data), "%v doesn't match %v", name, path)
+	}
+}
+
+func TestEncode_ReusesBuffer(t *testing.T) {
+	b := &Buffer{}
+	Encode(b, 1, messages["frame"])
+	capacity := cap(b.Bytes())
+
+	data := Encode(b, 2, &Lost{})
+	assert.Equal(t, HeaderSize, len(data))
+	assert.Equal(t, capacity, cap(data))
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	data := Encode(&Buffer{}, 1, &Won{Pot: "100"})
+
+	_, _, err := Decode(data[:len(data)-WordSize])
+	assert.NotNil(t, err)
+
+	_, _, err = Decode(data[:len(data)-1])
+	assert.NotNil(t, err)
+
+	bad := append([]byte{}, data...)
+	bad[0] = Version + 1
+	_, _, err = Decode(bad)
+	assert.NotNil(t, err)
+
+	bad = append([]byte{}, data...)
+	bad[HeaderSize] = 100 // Pot claims more runes than there are
+	_, _, err = Decode(bad)
+	assert.NotNil(t, err)
+
+	bad = append([]byte{}, data...)
+	bad[WordSize] = 99
+	_, _, err = Decode(bad)
+	assert.NotNil(t, err)
+}
+
+func TestDecode_InvalidFood(t *testing.T) {
+	frame := &Frame{ViewportSize: 2, Food: []FoodTile{{Row: 0, Col: 1}, {Row: 1, Col: 1}}}
+	data := Encode(&Buffer{}, 1, frame)
+	gaps := HeaderSize + 11*WordSize // After the viewport, the input sequence, the ticks left, the zone, an empty segment count and the food count
+
+	bad := append([]byte{}, data...)
+	bad[gaps+WordSize] = 0 // Same tile twice
+	_, _, err := Decode(bad)
+	assert.NotNil(t, err)
+
+	bad = append([]byte{}, data...)
+	bad[gaps+WordSize] = 3 // Past the last tile
+	_, _, err = Decode(bad)
+	assert.NotNil(t, err)
+}
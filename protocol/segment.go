@@ -0,0 +1,162 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Directions a run heads in, the same numbering as the engine's
+const (
+	Up = iota
+	Right
+	Down
+	Left
+)
+
+// An unbroken stretch of one snake's nodes that's in view
+// Starts at the node closest to the head and follows Runs toward the tail
+type SnakeSegment struct {
+	Player int32
+	Row    int32
+	Col    int32
+	Runs   []Run
+}
+
+// Length nodes in a row, each one step in Direction from the one before
+type Run struct {
+	Direction int32
+	Length    int32
+}
+
+// Adds the node at row, col to the end of the segment, it must be next to the current last node
+func (s *SnakeSegment) Extend(row, col int32) {
+	last := s.End()
+
+	var direction int32
+	switch {
+	case row == last.Row-1 && col == last.Col:
+		direction = Up
+	case row == last.Row && col == last.Col+1:
+		direction = Right
+	case row == last.Row+1 && col == last.Col:
+		direction = Down
+	case row == last.Row && col == last.Col-1:
+		direction = Left
+	default:
+		panic(fmt.Sprintf("%v,%v isn't next to %v,%v", row, col, last.Row, last.Col))
+	}
+
+	if n := len(s.Runs); n > 0 && s.Runs[n-1].Direction == direction {
+		s.Runs[n-1].Length++
+		return
+	}
+	s.Runs = append(s.Runs, Run{Direction: direction, Length: 1})
+}
+
+// The segment's last node
+func (s *SnakeSegment) End() SnakeTile {
+	end := SnakeTile{Player: s.Player, Row: s.Row, Col: s.Col}
+	for _, run := range s.Runs {
+		dRow, dCol := directionToRowCol(run.Direction)
+		end.Row += dRow * run.Length
+		end.Col += dCol * run.Length
+	}
+	return end
+}
+
+// Every node of the segment, head end first
+func (s *SnakeSegment) Tiles() []SnakeTile {
+	tiles := []SnakeTile{{Player: s.Player, Row: s.Row, Col: s.Col}}
+	row, col := s.Row, s.Col
+	for _, run := range s.Runs {
+		dRow, dCol := directionToRowCol(run.Direction)
+		for i := int32(0); i < run.Length; i++ {
+			row, col = row+dRow, col+dCol
+			tiles = append(tiles, SnakeTile{Player: s.Player, Row: row, Col: col})
+		}
+	}
+	return tiles
+}
+
+func directionToRowCol(direction int32) (int32, int32) {
+	switch direction {
+	case Up:
+		return -1, 0
+	case Right:
+		return 0, 1
+	case Down:
+		return 1, 0
+	default:
+		return 0, -1
+	}
+}
+
+// A run fits in one word, the length above the two direction bits
+func encodeSegments(e *encoder, segments []SnakeSegment) {
+	e.int(len(segments))
+	for _, segment := range segments {
+		e.int32(segment.Player)
+		e.int32(segment.Row)
+		e.int32(segment.Col)
+		e.int(len(segment.Runs))
+		for _, run := range segment.Runs {
+			e.int32(run.Length<<2 | run.Direction&3)
+		}
+	}
+}
+
+func decodeSegments(d *decoder) []SnakeSegment {
+	segments := make([]SnakeSegment, d.count(4))
+	for i := range segments {
+		segments[i] = SnakeSegment{Player: d.int32(), Row: d.int32(), Col: d.int32()}
+		segments[i].Runs = make([]Run, d.count(1))
+		for j := range segments[i].Runs {
+			word := d.int32()
+			segments[i].Runs[j] = Run{Direction: word & 3, Length: word >> 2}
+			if segments[i].Runs[j].Length < 1 && d.err == nil {
+				d.err = fmt.Errorf("run of length %v", segments[i].Runs[j].Length)
+			}
+		}
+	}
+	return segments
+}
+
+// Food is sent as positions within the viewport counted row major from its top left tile
+// Each word is the gap from the previous position, the first one's is from -1, so every word is at least 1
+func (f *Frame) encodeFood(e *encoder) {
+	positions := make([]int32, len(f.Food))
+	for i, food := range f.Food {
+		positions[i] = (food.Row-f.TopLeftRow)*f.ViewportSize + food.Col - f.TopLeftCol
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i] < positions[j]
+	})
+
+	e.int(len(positions))
+	previous := int32(-1)
+	for _, position := range positions {
+		e.int32(position - previous)
+		previous = position
+	}
+}
+
+func (f *Frame) decodeFood(d *decoder) {
+	f.Food = make([]FoodTile, d.count(1))
+	position := int32(-1)
+	for i := range f.Food {
+		gap := d.int32()
+		position += gap
+		if (gap < 1 || position >= f.ViewportSize*f.ViewportSize) && d.err == nil {
+			d.err = fmt.Errorf("food position %v outside the viewport", position)
+		}
+		if d.err != nil {
+			return
+		}
+
+		f.Food[i] = FoodTile{
+			Row: f.TopLeftRow + position/f.ViewportSize,
+			Col: f.TopLeftCol + position%f.ViewportSize,
+		}
+	}
+}
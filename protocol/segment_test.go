@@ -0,0 +1,22 @@
+package protocol
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSnakeSegment_Extend(t *testing.T) {
+	segment := &SnakeSegment{Player: 7, Row: 5, Col: 5}
+	tiles := []SnakeTile{{Player: 7, Row: 5, Col: 5}}
+
+	for _, next := range [][2]int32{{6, 5}, {7, 5}, {7, 4}, {6, 4}, {5, 4}, {4, 4}} {
+		segment.Extend(next[0], next[1])
+		tiles = append(tiles, SnakeTile{Player: 7, Row: next[0], Col: next[1]})
+	}
+
+	assert.Equal(t, []Run{{Direction: Down, Length: 2}, {Direction: Left, Length: 1}, {Direction: Up, Length: 3}}, segment.Runs)
+	assert.Equal(t, tiles, segment.Tiles())
+	assert.Equal(t, SnakeTile{Player: 7, Row: 4, Col: 4}, segment.End())
+
+	assert.Panics(t, func() { segment.Extend(4, 6) })
+}
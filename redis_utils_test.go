@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/go-redis/redis"
+	"github.com/op/go-logging"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Just enough of a Redis server for the commands the gameserver sends, sets are kept as hashes of empty strings
+func fakeRedis(t *testing.T) (*redis.Client, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	keys := map[string]map[string]string{}
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			var count int
+			if _, err := fmt.Fscanf(reader, "*%d\r\n", &count); err != nil {
+				return
+			}
+
+			args := make([]string, count)
+			for i := range args {
+				var length int
+				fmt.Fscanf(reader, "$%d\r\n", &length)
+				data := make([]byte, length+2)
+				io.ReadFull(reader, data)
+				args[i] = string(data[:length])
+			}
+
+			mutex.Lock()
+			reply := runCommand(keys, args)
+			mutex.Unlock()
+			io.WriteString(conn, reply)
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String()})
+	return client, func() {
+		client.Close()
+		listener.Close()
+	}
+}
+
+func runCommand(keys map[string]map[string]string, args []string) string {
+	if keys[args[1]] == nil {
+		keys[args[1]] = map[string]string{}
+	}
+	fields := keys[args[1]]
+
+	var reply []string
+	switch strings.ToLower(args[0]) {
+	case "hset":
+		fields[args[2]] = args[3]
+		return ":1\r\n"
+	case "hget":
+		value, present := fields[args[2]]
+		if !present {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%v\r\n%v\r\n", len(value), value)
+	case "hincrby":
+		value, _ := strconv.Atoi(fields[args[2]])
+		incr, _ := strconv.Atoi(args[3])
+		fields[args[2]] = strconv.Itoa(value + incr)
+		return fmt.Sprintf(":%v\r\n", value+incr)
+	case "hgetall":
+		for field, value := range fields {
+			reply = append(reply, field, value)
+		}
+	case "sadd":
+		for _, member := range args[2:] {
+			fields[member] = ""
+		}
+		return fmt.Sprintf(":%v\r\n", len(args)-2)
+	case "smembers":
+		for member := range fields {
+			reply = append(reply, member)
+		}
+		sort.Strings(reply)
+	case "del":
+		delete(keys, args[1])
+		return ":1\r\n"
+	default:
+		return fmt.Sprintf("-ERR unknown command '%v'\r\n", args[0])
+	}
+
+	message := fmt.Sprintf("*%v\r\n", len(reply))
+	for _, value := range reply {
+		message += fmt.Sprintf("$%v\r\n%v\r\n", len(value), value)
+	}
+	return message
+}
+
+// A lobby for signups players with its map created, both Redis clients share one fake server
+// The returned func stops the game loop if one was started and shuts it all down
+func newTestState(t *testing.T, signups int) (*State, func()) {
+	client, closeRedis := fakeRedis(t)
+
+	dir, err := ioutil.TempDir("", "replays")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.ReplayDir = dir
+	config.Seed = 1
+
+	s := &State{
+		GameID:          "10000",
+		GameserverRedis: client,
+		PlayerRedis:     client,
+		Log:             logging.MustGetLogger("test"),
+		InitialConfig:   config,
+		SignupCount:     signups,
+		Spectators:      map[int]*Spectator{},
+	}
+	s.CreateMap()
+
+	return s, func() {
+		s.StopGame()
+		closeRedis()
+		os.RemoveAll(dir)
+	}
+}
+
+// Signs a paid token up for the lobby the way the matchmaker does
+func signUp(s *State, token string) {
+	s.GameserverRedis.SAdd(s.GameID+":signups", token)
+	s.PlayerRedis.HSet(token, "status", "paid")
+}
@@ -0,0 +1,139 @@
+// Package replay records everything needed to regenerate a game: the seed, spawns and every tick's inputs
+// Files are gzipped JSON so support staff can still zcat one when a verifier run looks wrong
+package replay
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"github.com/moneygames-io/gameserver/engine"
+	"io"
+	"sort"
+)
+
+type Replay struct {
+	GameID      string
+	Config      engine.Config
+	SignupCount int
+
+	// Players in the order they joined, which is the order the engine spawned them in
+	Players []Player
+
+	// One entry per engine.Game.Step
+	Ticks []Tick
+
+	// What the server paid out, filled in by SendWin
+	Result *Result
+}
+
+type Player struct {
+	ID   engine.PlayerID
+	Name string
+
+	// Public identifier sent to clients, the token itself is never written
+	Hash int32
+
+	// Server controlled, never paid out
+	Bot bool
+
+	// Where the engine spawned the snake's head
+	Row int
+	Col int
+}
+
+// Inputs applied during one step, sorted by player
+type Tick []Input
+
+type Input struct {
+	Player    engine.PlayerID `json:"p"`
+	Turns     []int           `json:"t,omitempty"`
+	Sprinting bool            `json:"s,omitempty"`
+	Forfeit   bool            `json:"f,omitempty"`
+}
+
+type Result struct {
+	Winner engine.PlayerID
+
+	// engine.Game.Standings when the game ended
+	Standings []engine.PlayerID
+	Pot       string
+}
+
+func New(gameID string, config engine.Config, signupCount int) *Replay {
+	return &Replay{
+		GameID:      gameID,
+		Config:      config,
+		SignupCount: signupCount,
+	}
+}
+
+// Records a freshly spawned player, call right after engine.Game.AddPlayer
+func (r *Replay) RecordSpawn(g *engine.Game, id engine.PlayerID, name string, hash int32, bot bool) {
+	head := g.ActivePlayers[id]
+	r.Players = append(r.Players, Player{
+		ID:   id,
+		Name: name,
+		Hash: hash,
+		Bot:  bot,
+		Row:  head.Row,
+		Col:  head.Col,
+	})
+}
+
+// Records the inputs about to be handed to engine.Game.Step
+func (r *Replay) RecordTick(inputs map[engine.PlayerID]engine.Input) {
+	tick := make(Tick, 0, len(inputs))
+	for id, input := range inputs {
+		tick = append(tick, Input{
+			Player:    id,
+			Turns:     input.Turns,
+			Sprinting: input.Sprinting,
+			Forfeit:   input.Forfeit,
+		})
+	}
+
+	sort.Slice(tick, func(i, j int) bool {
+		return tick[i].Player < tick[j].Player
+	})
+
+	r.Ticks = append(r.Ticks, tick)
+}
+
+// Inputs in the form engine.Game.Step takes them
+func (t Tick) Inputs() map[engine.PlayerID]engine.Input {
+	inputs := make(map[engine.PlayerID]engine.Input, len(t))
+	for _, input := range t {
+		inputs[input.Player] = engine.Input{
+			Turns:     input.Turns,
+			Sprinting: input.Sprinting,
+			Forfeit:   input.Forfeit,
+		}
+	}
+	return inputs
+}
+
+func (r *Replay) Write(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+
+	err := json.NewEncoder(zw).Encode(r)
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func Read(r io.Reader) (*Replay, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	replay := &Replay{}
+	err = json.NewDecoder(zr).Decode(replay)
+	if err != nil {
+		return nil, err
+	}
+
+	return replay, nil
+}
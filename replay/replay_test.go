@@ -0,0 +1,73 @@
+package replay
+
+import (
+	"bytes"
+	"github.com/moneygames-io/gameserver/engine"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReplay_WriteRead(t *testing.T) {
+	config := engine.Config{ScalingFactor: 10, FoodPerPlayer: 2, SprintFactor: 2, Seed: 7}
+	g := engine.New(config, 2)
+
+	r := New("10000", config, 2)
+	for _, name := range []string{"a", "b"} {
+		id := g.AddPlayer()
+		r.RecordSpawn(g, id, name, int32(id)+100, false)
+	}
+	r.RecordTick(map[engine.PlayerID]engine.Input{
+		1: {Turns: []int{engine.Left, engine.Down}, Sprinting: true},
+		0: {Turns: []int{engine.Right}},
+	})
+	r.Result = &Result{Winner: 1, Standings: []engine.PlayerID{1, 0}, Pot: "500"}
+
+	buffer := &bytes.Buffer{}
+	assert.Nil(t, r.Write(buffer))
+
+	read, err := Read(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, r, read)
+	assert.Equal(t, engine.PlayerID(0), read.Ticks[0][0].Player)
+	assert.Equal(t, engine.Input{Turns: []int{engine.Left, engine.Down}, Sprinting: true}, read.Ticks[0].Inputs()[1])
+}
+
+func TestReplay_Verify(t *testing.T) {
+	config := engine.Config{ScalingFactor: 10, FoodPerPlayer: 2, SprintFactor: 2, Seed: 3}
+	g := engine.New(config, 4)
+	r := New("10000", config, 4)
+
+	for i := 0; i < 3; i++ {
+		id := g.AddPlayer()
+		r.RecordSpawn(g, id, "", 0, i == 0)
+	}
+
+	// Everyone heads for a different wall until one snake is left
+	for tick := 0; !g.Over(); tick++ {
+		inputs := map[engine.PlayerID]engine.Input{}
+		for id := range g.ActivePlayers {
+			inputs[id] = engine.Input{Turns: []int{int(id)}}
+		}
+		r.RecordTick(inputs)
+		g.Step(inputs)
+	}
+
+	standings := g.Standings()
+	assert.Len(t, standings, 3)
+	winner, found := r.Winner(g)
+	assert.True(t, found)
+	assert.NotEqual(t, engine.PlayerID(0), winner)
+	r.Result = &Result{Winner: winner, Standings: standings}
+
+	_, err := r.Verify()
+	assert.Nil(t, err)
+
+	r.Result.Winner = 0
+	_, err = r.Verify()
+	assert.NotNil(t, err)
+
+	r.Result.Winner = winner
+	r.Players[0].Row++
+	_, err = r.Verify()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,79 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"github.com/moneygames-io/gameserver/engine"
+)
+
+// Re-runs the recorded game headlessly and returns the game as it stood after the last tick
+// Fails if the engine doesn't spawn the players where the server did
+func (r *Replay) Simulate() (*engine.Game, error) {
+	if _, err := engine.NewMode(r.Config); err != nil {
+		return nil, err
+	}
+
+	g := engine.New(r.Config, r.SignupCount)
+
+	for _, player := range r.Players {
+		id := g.AddPlayer()
+		head := g.ActivePlayers[id]
+
+		if id != player.ID || head.Row != player.Row || head.Col != player.Col {
+			return g, fmt.Errorf("player %v spawned as %v at (%v, %v), recorded as %v at (%v, %v)",
+				player.Name, id, head.Row, head.Col, player.ID, player.Row, player.Col)
+		}
+	}
+
+	for _, tick := range r.Ticks {
+		g.Step(tick.Inputs())
+	}
+
+	return g, nil
+}
+
+// Checks that re-running the game ends with the rankings and winner the server paid out
+func (r *Replay) Verify() (*engine.Game, error) {
+	if r.Result == nil {
+		return nil, errors.New("replay has no result, the game never paid out")
+	}
+
+	g, err := r.Simulate()
+	if err != nil {
+		return g, err
+	}
+
+	standings := g.Standings()
+	if len(standings) != len(r.Result.Standings) {
+		return g, fmt.Errorf("simulated standings %v, recorded %v", standings, r.Result.Standings)
+	}
+
+	for i := range standings {
+		if standings[i] != r.Result.Standings[i] {
+			return g, fmt.Errorf("simulated standings %v, recorded %v", standings, r.Result.Standings)
+		}
+	}
+
+	winner, found := r.Winner(g)
+	if !found || winner != r.Result.Winner {
+		return g, fmt.Errorf("simulated standings %v don't make %v the winner", standings, r.Result.Winner)
+	}
+
+	return g, nil
+}
+
+// Best placed player that isn't a bot and is still alive in g
+func (r *Replay) Winner(g *engine.Game) (engine.PlayerID, bool) {
+	bots := map[engine.PlayerID]bool{}
+	for _, player := range r.Players {
+		bots[player.ID] = player.Bot
+	}
+
+	for _, id := range g.Standings() {
+		if _, alive := g.ActivePlayers[id]; alive && !bots[id] {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Where this game's replay lives once the game is over
+func (s *State) ReplayPath() string {
+	return filepath.Join(s.InitialConfig.ReplayDir, s.GameID+".replay.gz")
+}
+
+// Writes the replay to a temporary file first so ServeReplay never sees half of one
+func (s *State) SaveReplay() {
+	err := os.MkdirAll(s.InitialConfig.ReplayDir, 0755)
+	if err != nil {
+		s.Log.Error("Could not create replay dir: %v", err)
+		return
+	}
+
+	path := s.ReplayPath()
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		s.Log.Error("Could not create replay: %v", err)
+		return
+	}
+
+	err = s.Replay.Write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+
+	if err != nil {
+		s.Log.Error("Could not write replay: %v", err)
+		return
+	}
+
+	s.Log.Info("Replay written to %v", path)
+}
+
+// Lets anyone download the replay of this game once it has ended
+func (s *State) ServeReplay(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Access-Control-Allow-Origin", "*")
+
+	path := s.ReplayPath()
+	if _, err := os.Stat(path); err != nil {
+		http.Error(writer, "No replay", 404)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/gzip")
+	writer.Header().Set("Content-Disposition", "attachment; filename="+filepath.Base(path))
+	http.ServeFile(writer, request, path)
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"github.com/go-redis/redis"
+	"github.com/moneygames-io/gameserver/bot"
+	"github.com/moneygames-io/gameserver/engine"
+	"github.com/moneygames-io/gameserver/protocol"
+	"github.com/moneygames-io/gameserver/replay"
 	"github.com/op/go-logging"
 	"github.com/pions/webrtc"
 	"sync"
+	"time"
 )
 
 // Represents the state of the current game, root node of the object graph
@@ -19,9 +24,17 @@ type State struct {
 	GameID string
 
 	// Used to provide status updates about "this" game's state
+	// The hash at GameID holds:
+	//   - players, pot and the profile fields written by the matchmaker, see LoadProfile
+	//   - status: idle, ready, then aborted if the game is called off or nobody survives it, payments refunds every signup of an aborted game
+	//   - seed and unconfirmed, written by this server
+	// The set at GameID:signups holds the token of every player the matchmaker signed up, it has to be written before players
+	// The set at GameID holds the tokens that joined
 	GameserverRedis *redis.Client
 
 	// Used to provide information related to players
+	// The hash at each token holds status, game and unconfirmed
+	// status is paid until the player joins, then in game, then won, lost, aborted or no show, payments settles on the last four
 	PlayerRedis *redis.Client
 
 	// Logging
@@ -45,8 +58,20 @@ type State struct {
 	// Whether the game has started or not
 	Running bool
 
-	// 2D world which all the game logic operates on
-	World *Map
+	// Closed once a started game loop has returned
+	LoopDone chan struct{}
+
+	// Set once the game starts or is aborted, nobody new can join after this
+	LobbyClosed bool
+
+	// The rules and world this server is hosting, see the engine package
+	Game *engine.Game
+
+	// Players that have been spawned, keyed by their id in Game
+	Players map[engine.PlayerID]*Player
+
+	// Record of Game, written to ReplayDir once the game ends
+	Replay *replay.Replay
 
 	// Collection of spectators
 	// TODO: Don't make this a map
@@ -58,86 +83,103 @@ type State struct {
 
 // Used to store all the information regarding a player
 type Player struct {
+	ID             engine.PlayerID
 	Name           string
 	Token          string
-	Snake          *SnakeNode
 	SpectatorCount int
 	Input          *Input
 	Message        *Message
-	Connection     *webrtc.RTCDataChannel
+	Buffer         protocol.Buffer
+	Connection     Transport
+
+	// Sequence of the last input applied, and how many inputs were rejected as malformed
+	InputSequence  uint32
+	RejectedInputs int
+
+	// Latest frame the client says it has, and the frames it could still acknowledge by tick
+	AckedTick    int
+	SentFrames   map[int]*protocol.View
+	LastKeyframe int
+
+	// Steers the snake for server controlled players, nil for humans
+	Bot bot.Strategy
+
+	// Whether Connection can be sent to, and when it stopped being so
+	Connected      bool
+	DisconnectedAt time.Time
+
+	// Set once the player has been gone too long, the engine kills their snake next tick
+	Forfeit bool
+
+	// Snakes that died with this player as the killer
+	Kills int
 }
 
 type Input struct {
+	// Latest direction the player asked for, and the turns they made since the last tick
 	Direction int
+	Turns     []int
 	Sprinting bool
 	ZoomLevel int
 }
 
 type Spectator struct {
 	Name        string
-	Connection  *webrtc.RTCDataChannel
+	Connection  Transport
 	CurrentView *Player
+	Buffer      protocol.Buffer
 }
 
-type Map struct {
-	Tiles         [][]MapObject
-	ActivePlayers map[*Player]*SnakeNode
-	LostPlayers   map[*Player]*SnakeNode
-}
-
-type OutOfBounds struct{}
-
-type MapObject interface{}
-
-type SnakeNode struct {
-	Player *Player
-	Length int
-	Next   *SnakeNode
-	Row    int
-	Col    int
-}
-
-type FoodNode struct {
-	Row int
-	Col int
-}
-
+// Tunables for a game, see config.go for how these are loaded
 type Config struct {
-	ScalingFactor   int
-	FoodPerPlayer   int
-	SprintFactor    int
-	LeaderboardSize int
-	FrameRate       int
-	DefaultZoom     int
-	RTCSettings     webrtc.RTCConfiguration
+	Mode            string   `json:"mode"`
+	DurationTicks   int      `json:"duration_ticks"`
+	ScalingFactor   int      `json:"scaling_factor"`
+	FoodPerPlayer   int      `json:"food_per_player"`
+	SprintFactor    int      `json:"sprint_factor"`
+	QueueDepth      int      `json:"queue_depth"` // 0 doesn't limit it
+	LeaderboardSize int      `json:"leaderboard_size"`
+	FrameRate       int      `json:"frame_rate"`
+	DefaultZoom     int      `json:"default_zoom"`
+	ICEServers      []string `json:"ice_servers"`
+
+	// Ticks between the safe zone closing in by a tile on every side, 0 never closes it, and the side it stops at
+	ZoneInterval int `json:"zone_interval"`
+	ZoneMinSize  int `json:"zone_min_size"`
+
+	// Range of zoom levels players may pick, inputs outside it are rejected
+	MinZoom int `json:"min_zoom"`
+	MaxZoom int `json:"max_zoom"`
+
+	// Seeds the game's RNG, 0 picks one from the clock
+	Seed int64 `json:"seed"`
+
+	// Where finished games' replays are written
+	ReplayDir string `json:"replay_dir"`
+
+	// Seconds players have to join once the lobby is ready, 0 waits forever
+	JoinTimeout int `json:"join_timeout"`
+
+	// Fewest players that must have joined by JoinTimeout for the game to go ahead
+	MinPlayers int `json:"min_players"`
+
+	// Slots still empty at JoinTimeout get a bot of this difficulty, empty leaves them empty
+	BotDifficulty string `json:"bot_difficulty"`
+
+	// Most ticks between whole frames, the ones in between are sent as deltas
+	KeyframeInterval int `json:"keyframe_interval"`
+
+	// Seconds a disconnected player has to come back before DisconnectPolicy applies
+	// "kill" forfeits their snake, "straight" leaves it running in a straight line
+	DisconnectGrace  int    `json:"disconnect_grace"`
+	DisconnectPolicy string `json:"disconnect_policy"`
+
+	// Built from ICEServers once the config is loaded
+	RTCSettings webrtc.RTCConfiguration `json:"-"`
 }
 
+// What a player is sent each tick, built by GenerateMessageModels and SerializeMessages
 type Message struct {
-	TopLeft      *Coordinate
-	ViewportSize int
-	MapSize      int
-	LeaderMap    []*Player
-	Perspective  map[MapObject]bool
-	Serialized   []int32
+	Frame      *protocol.Frame
+	Serialized []byte
 }
-
-type Coordinate struct {
-	Row int
-	Col int
-}
-
-func (m *Map) Get(c *Coordinate) MapObject {
-	row := c.Row
-	col := c.Col
-
-	if row < 0 || col < 0 || row >= len(m.Tiles) || col >= len(m.Tiles[0]) {
-		return &OutOfBounds{}
-	} else {
-		return m.Tiles[row][col]
-	}
-}
-
-// Program-wide constants
-const FrameMessage = 1
-const WonMessage = 2
-const LostMessage = 3
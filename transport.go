@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/pions/webrtc"
+	"github.com/pions/webrtc/pkg/datachannel"
+	"net/http"
+	"sync"
+)
+
+// How messages reach a client, the game sends through this without knowing what's underneath
+// Values are compared to tell a player's current connection from one they've replaced
+// For both sends the caller may reuse data once they return
+type Transport interface {
+	// Queues data to arrive in order, however long that takes, for messages the client can't miss
+	SendReliable(data []byte) error
+
+	// Queues data that may be dropped or overtaken, for frames the next one makes stale anyway
+	SendUnreliable(data []byte) error
+
+	// Hangs up, safe to call more than once
+	Close() error
+}
+
+// Labels of the data channels on a player's peer connection
+// The client opens control, if it names its channel anything else the first one it opens is treated as control
+// The server opens frames itself so control messages don't wait in the same stream as frames
+// pions v1.2.0 opens every data channel ordered and reliable, so both are, frames included
+const (
+	ControlChannel = "control"
+	FramesChannel  = "frames"
+)
+
+// A WebRTC peer connection and its data channels
+// Until the frames channel is open, frames go over control
+type RTCTransport struct {
+	Peer *webrtc.RTCPeerConnection
+
+	mutex   sync.Mutex
+	control sender
+	frames  sender
+}
+
+// The part of a data channel RTCTransport sends through
+type sender interface {
+	Send(payload datachannel.Payload) error
+}
+
+// Opens the frames channel on peer, it has to be called before the answer is created
+func NewFramesChannel(peer *webrtc.RTCPeerConnection) (*webrtc.RTCDataChannel, error) {
+	return peer.CreateDataChannel(FramesChannel, nil)
+}
+
+// Files an open channel by its label, true only for the first control channel, the one the transport can't work without
+// Later channels are left unused so they can't onboard the player again
+func (t *RTCTransport) AddChannel(d *webrtc.RTCDataChannel) bool {
+	return t.addChannel(d.Label, d)
+}
+
+func (t *RTCTransport) addChannel(label string, channel sender) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if label == FramesChannel {
+		if t.frames == nil {
+			t.frames = channel
+		}
+		return false
+	}
+
+	if t.control != nil {
+		return false
+	}
+
+	t.control = channel
+	return true
+}
+
+func (t *RTCTransport) SendReliable(data []byte) error {
+	t.mutex.Lock()
+	channel := t.control
+	t.mutex.Unlock()
+
+	return channel.Send(datachannel.PayloadBinary{Data: copyBytes(data)})
+}
+
+func (t *RTCTransport) SendUnreliable(data []byte) error {
+	t.mutex.Lock()
+	channel := t.frames
+	if channel == nil {
+		channel = t.control
+	}
+	t.mutex.Unlock()
+
+	return channel.Send(datachannel.PayloadBinary{Data: copyBytes(data)})
+}
+
+func (t *RTCTransport) Close() error {
+	return t.Peer.Close()
+}
+
+// The bytes of a data channel message, whichever kind the client sent
+func payloadBytes(payload datachannel.Payload) []byte {
+	switch p := payload.(type) {
+	case *datachannel.PayloadBinary:
+		return p.Data
+	case *datachannel.PayloadString:
+		return p.Data
+	default:
+		return nil
+	}
+}
+
+// Messages waiting to go out on a WebSocket before a client is too far behind
+// Frames are dropped once half of them are taken, the rest is kept for reliable messages
+const webSocketOutbox = 16
+
+var errOutboxFull = errors.New("websocket outbox full")
+
+// A WebSocket, for clients on networks that block the UDP WebRTC needs
+// Everything goes over the one TCP stream, frames are only ever dropped before they're queued
+// Writes happen on their own goroutine so a slow client can't hold up the game loop
+// A client that falls so far behind a reliable message can't be queued is hung up on, it has to reconnect
+type WebSocketTransport struct {
+	Conn *websocket.Conn
+
+	outbox chan []byte
+	done   chan struct{}
+	once   sync.Once
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true }, // Same as corsHandler, any site can host the client
+}
+
+func NewWebSocketTransport(conn *websocket.Conn) *WebSocketTransport {
+	t := &WebSocketTransport{
+		Conn:   conn,
+		outbox: make(chan []byte, webSocketOutbox),
+		done:   make(chan struct{}),
+	}
+	go t.write()
+	return t
+}
+
+func (t *WebSocketTransport) SendUnreliable(data []byte) error {
+	if len(t.outbox) >= webSocketOutbox/2 {
+		return errors.New("websocket outbox full, frame dropped")
+	}
+	return t.send(data)
+}
+
+func (t *WebSocketTransport) SendReliable(data []byte) error {
+	err := t.send(data)
+	if err == errOutboxFull {
+		_ = t.Close()
+	}
+	return err
+}
+
+func (t *WebSocketTransport) send(data []byte) error {
+	select {
+	case <-t.done:
+		return errors.New("websocket closed")
+	default:
+	}
+
+	select {
+	case t.outbox <- copyBytes(data):
+		return nil
+	default:
+		return errOutboxFull
+	}
+}
+
+func (t *WebSocketTransport) Close() error {
+	var err error
+	t.once.Do(func() {
+		close(t.done)
+		err = t.Conn.Close()
+	})
+	return err
+}
+
+func (t *WebSocketTransport) write() {
+	for {
+		select {
+		case <-t.done:
+			return
+		case data := <-t.outbox:
+			if t.Conn.WriteMessage(websocket.BinaryMessage, data) != nil {
+				_ = t.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/moneygames-io/gameserver/protocol"
+	"github.com/pions/webrtc/pkg/datachannel"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Keeps everything sent through it for tests to look at
+type recordingTransport struct {
+	mutex      sync.Mutex
+	reliable   [][]byte
+	unreliable [][]byte
+	closed     bool
+}
+
+func (t *recordingTransport) SendReliable(data []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.reliable = append(t.reliable, copyBytes(data))
+	return nil
+}
+
+func (t *recordingTransport) SendUnreliable(data []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.unreliable = append(t.unreliable, copyBytes(data))
+	return nil
+}
+
+func (t *recordingTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (t *recordingTransport) isClosed() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.closed
+}
+
+// Types of the messages sent reliably so far
+func (t *recordingTransport) reliableTypes() []protocol.MessageType {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return messageTypes(t.reliable)
+}
+
+// Types of the messages sent unreliably so far
+func (t *recordingTransport) unreliableTypes() []protocol.MessageType {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return messageTypes(t.unreliable)
+}
+
+func messageTypes(messages [][]byte) []protocol.MessageType {
+	var types []protocol.MessageType
+	for _, data := range messages {
+		header, _, _ := protocol.Decode(data)
+		types = append(types, header.Type)
+	}
+	return types
+}
+
+// The server's end of a WebSocket to a client, both are closed when the test ends
+func webSocketPair(t *testing.T) (*websocket.Conn, *websocket.Conn, func()) {
+	accepted := make(chan *websocket.Conn)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		assert.Nil(t, err)
+		accepted <- conn
+	}))
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := <-accepted
+
+	return conn, client, func() {
+		client.Close()
+		conn.Close()
+		server.Close()
+	}
+}
+
+func TestWebSocketTransport(t *testing.T) {
+	conn, client, done := webSocketPair(t)
+	defer done()
+
+	transport := NewWebSocketTransport(conn)
+	data := []byte{1, 2, 3, 4}
+	assert.Nil(t, transport.SendReliable(data))
+	data[0] = 9 // Sends must not hold on to the caller's buffer
+
+	kind, received, err := client.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, websocket.BinaryMessage, kind)
+	assert.Equal(t, []byte{1, 2, 3, 4}, received)
+
+	assert.Nil(t, transport.Close())
+	assert.Nil(t, transport.Close())
+	assert.NotNil(t, transport.SendUnreliable(data))
+}
+
+func TestWebSocketTransport_Overflow(t *testing.T) {
+	conn, client, done := webSocketPair(t)
+	defer done()
+
+	// Nothing writes the outbox out, like a client that stopped reading
+	transport := &WebSocketTransport{
+		Conn:   conn,
+		outbox: make(chan []byte, webSocketOutbox),
+		done:   make(chan struct{}),
+	}
+
+	for i := 0; i < webSocketOutbox/2; i++ {
+		assert.Nil(t, transport.SendUnreliable([]byte{1}))
+	}
+	assert.NotNil(t, transport.SendUnreliable([]byte{1}))
+
+	// Frames left room for reliable messages, and one that doesn't fit hangs up rather than being dropped
+	for i := 0; i < webSocketOutbox/2; i++ {
+		assert.Nil(t, transport.SendReliable([]byte{2}))
+	}
+	assert.NotNil(t, transport.SendReliable([]byte{2}))
+	assert.NotNil(t, transport.SendReliable([]byte{2}))
+
+	_, _, err := client.ReadMessage()
+	assert.NotNil(t, err)
+}
+
+// Stands in for a data channel, keeping what's sent on it
+type recordingChannel struct {
+	sent [][]byte
+}
+
+func (c *recordingChannel) Send(payload datachannel.Payload) error {
+	c.sent = append(c.sent, payload.(datachannel.PayloadBinary).Data)
+	return nil
+}
+
+func TestRTCTransport_Channels(t *testing.T) {
+	transport := &RTCTransport{}
+	control, frames := &recordingChannel{}, &recordingChannel{}
+
+	assert.True(t, transport.addChannel(ControlChannel, control))
+
+	// Frames go over control until the frames channel opens
+	data := []byte{1}
+	assert.Nil(t, transport.SendUnreliable(data))
+	data[0] = 9
+	assert.Equal(t, [][]byte{{1}}, control.sent)
+
+	assert.False(t, transport.addChannel(FramesChannel, frames))
+	assert.Nil(t, transport.SendUnreliable([]byte{2}))
+	assert.Nil(t, transport.SendReliable([]byte{3}))
+	assert.Equal(t, [][]byte{{2}}, frames.sent)
+	assert.Equal(t, [][]byte{{1}, {3}}, control.sent)
+
+	// Channels opened after the first aren't used and don't onboard the player again
+	assert.False(t, transport.addChannel(ControlChannel, &recordingChannel{}))
+	assert.False(t, transport.addChannel("chat", &recordingChannel{}))
+	assert.False(t, transport.addChannel(FramesChannel, &recordingChannel{}))
+	assert.Nil(t, transport.SendReliable([]byte{4}))
+	assert.Nil(t, transport.SendUnreliable([]byte{5}))
+	assert.Equal(t, [][]byte{{1}, {3}, {4}}, control.sent)
+	assert.Equal(t, [][]byte{{2}, {5}}, frames.sent)
+
+	// Clients that only open a channel of their own get everything on it
+	other := &recordingChannel{}
+	assert.True(t, (&RTCTransport{}).addChannel("chat", other))
+}